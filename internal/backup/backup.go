@@ -0,0 +1,188 @@
+// Package backup takes and restores consistent copies of a sqlite book using
+// sqlite's online backup API.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gt/internal/store"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var ErrBackupNotFound = errors.New("backup not found")
+
+const idLayout = "20060102T150405.000"
+
+type Backup struct {
+	ID   string
+	Path string
+	Time time.Time
+	Size int64
+}
+
+// Dir returns the default backup directory of the book at bookPath.
+func Dir(bookPath string) string {
+	return bookPath + ".gt-backups"
+}
+
+// Create copies the database db into a new backup file in dir.
+func Create(ctx context.Context, db *sql.DB, dir, bookPath string) (*Backup, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	id := now.Format(idLayout)
+	path := filepath.Join(dir, fileName(bookPath, id))
+
+	dest, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer dest.Close()
+
+	if err := copyDatabase(ctx, dest, db); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backup{ID: id, Path: path, Time: now, Size: info.Size()}, nil
+}
+
+// List returns the backups of the book at bookPath in dir, oldest first.
+func List(dir, bookPath string) ([]*Backup, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*Backup{}, nil
+		}
+		return nil, err
+	}
+
+	prefix := filepath.Base(bookPath) + "."
+	backups := []*Backup{}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+
+		id := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".bak")
+		t, err := time.Parse(idLayout, id)
+		if err != nil {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		backups = append(backups, &Backup{ID: id, Path: filepath.Join(dir, name), Time: t, Size: info.Size()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.Before(backups[j].Time)
+	})
+
+	return backups, nil
+}
+
+// Get returns the backup of the book at bookPath with id.
+func Get(dir, bookPath, id string) (*Backup, error) {
+	backups, err := List(dir, bookPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, id)
+}
+
+// Rotate removes all but the newest keep backups of the book at bookPath.
+func Rotate(dir, bookPath string, keep int) error {
+	backups, err := List(dir, bookPath)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > keep {
+		if err := os.Remove(backups[0].Path); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// Restore overwrites the database db with the contents of b.
+func Restore(ctx context.Context, db *sql.DB, b *Backup) error {
+	src, err := sql.Open("sqlite3", store.ReadOnlyDSN(b.Path))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return copyDatabase(ctx, db, src)
+}
+
+func fileName(bookPath, id string) string {
+	return fmt.Sprintf("%s.%s.bak", filepath.Base(bookPath), id)
+}
+
+// copyDatabase copies the main database of src into dest.
+func copyDatabase(ctx context.Context, dest, src *sql.DB) error {
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			destSQLiteConn, ok := destDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unsupported database driver %T", destDriverConn)
+			}
+			srcSQLiteConn, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unsupported database driver %T", srcDriverConn)
+			}
+
+			b, err := destSQLiteConn.Backup("main", srcSQLiteConn, "main")
+			if err != nil {
+				return err
+			}
+
+			if _, err := b.Step(-1); err != nil {
+				b.Finish()
+				return err
+			}
+
+			return b.Finish()
+		})
+	})
+}
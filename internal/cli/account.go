@@ -6,6 +6,7 @@ import (
 	"gt/internal/render"
 	"gt/internal/store"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/spf13/cobra"
@@ -18,6 +19,7 @@ func accountCmd(cli *cli) *cobra.Command {
 	cmd.AddCommand(getAccountCmd(cli))
 	cmd.AddCommand(listAccountCmd(cli))
 	cmd.AddCommand(updateAccountCmd(cli))
+	cmd.AddCommand(registerAccountCmd(cli))
 	return cmd
 }
 
@@ -29,9 +31,10 @@ func updateAccountCmd(cli *cli) *cobra.Command {
 		output        string
 	}
 	var cmd = &cobra.Command{
-		Use:   "update [account]",
-		Short: "Update an account",
-		Args:  cobra.ExactArgs(1),
+		Use:         "update [account]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Update an account",
+		Args:        cobra.ExactArgs(1),
 		Long: `Update an existing account with new properties.
 
 This command allows you to modify an account's name, description, 
@@ -46,14 +49,7 @@ using its GUID or full account name path.`,
 			var err error
 			guidOrAccountName := args[0]
 
-			tx, err := cli.db.BeginTx(cmd.Context(), nil)
-			if err != nil {
-				return err
-			}
-			defer tx.Rollback()
-
 			s := store.NewStore(cli.db)
-			txStore := s.WithTx(tx)
 
 			account, err := s.Accounts.Get(cmd.Context(), guidOrAccountName)
 			if err != nil {
@@ -107,14 +103,16 @@ using its GUID or full account name path.`,
 				account.Description = &flags.description
 			}
 
-			if err := txStore.Accounts.Update(cmd.Context(), account); err != nil {
-				return err
-			}
-
-			if err := tx.Commit(); err != nil {
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				return txStore.Accounts.Update(cmd.Context(), account)
+			})
+			if err != nil || !committed {
 				return err
 			}
 
+			// The account tree of s was loaded before the update so the account
+			// is read back through a new store.
+			s = store.NewStore(cli.db)
 			account, err = s.Accounts.Get(cmd.Context(), account.GUID)
 			if err != nil {
 				return err
@@ -218,3 +216,70 @@ func listAccountCmd(cli *cli) *cobra.Command {
 	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
 	return cmd
 }
+
+func registerAccountCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		from   string
+		to     string
+		filter string
+		output string
+	}
+	var cmd = &cobra.Command{
+		Use:   "register [account]",
+		Short: "Show the register of an account with a running balance",
+		Args:  cobra.ExactArgs(1),
+		Long: `Show the register of an account, one row per split on the account in
+post date order with the counter account and a running balance.
+
+The balance starts from the account's balance before --from. Amounts
+are in the account's commodity. With --filter only the splits of
+matching transactions are shown, the balance still includes the others.`,
+		Example: `  gt account register assets:bank --from 2024-05-01 --to 2024-05-31`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := store.NewStore(cli.db)
+			account, err := getAccount(cmd.Context(), &s, args[0])
+			if err != nil {
+				return err
+			}
+
+			var from, to *time.Time
+			if flags.from != "" {
+				t, err := time.Parse("2006-01-02", flags.from)
+				if err != nil {
+					return err
+				}
+				from = &t
+			}
+			if flags.to != "" {
+				t, err := time.Parse("2006-01-02", flags.to)
+				if err != nil {
+					return err
+				}
+				// --to is inclusive.
+				t = t.AddDate(0, 0, 1)
+				to = &t
+			}
+
+			entries, err := s.Register(cmd.Context(), account, from, to)
+			if err != nil {
+				return err
+			}
+			entries, err = filterRegister(cmd.Context(), &s, account, entries, flags.filter, nil)
+			if err != nil {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			return r.Render(cmd.OutOrStdout(), entries)
+		},
+	}
+	cmd.Flags().StringVar(&flags.from, "from", "", "Only show splits posted on or after this date")
+	cmd.Flags().StringVar(&flags.to, "to", "", "Only show splits posted on or before this date")
+	cmd.Flags().StringVar(&flags.filter, "filter", "", FlagsUsageFilter)
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	return cmd
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"gt/internal/store"
 	"os"
 	"testing"
@@ -267,8 +268,166 @@ func TestUpdateAccountCmd(t *testing.T) {
 			t.Fatalf("expected name test2 but got %s", resp.Name)
 		}
 
+		if resp.FullName != "Expenses:test2" {
+			t.Fatalf("expected full name Expenses:test2 but got %s", resp.FullName)
+		}
+
 		if *resp.Description != "test-2" {
 			t.Fatalf("expected description test-2 but got %s", resp.Name)
 		}
 	})
+	t.Run("dry run", func(t *testing.T) {
+		ctx := context.Background()
+		db, cleanup := newTestingDB(ctx, t)
+		defer cleanup()
+
+		insertTestingAccount(ctx, db, t, "2", "test1", "EXPENSE", "EXPENSESGUID")
+
+		c := &cli{db: db, dryRun: true}
+		out, err := executeCommand(updateAccountCmd(c), "expenses:test1", "--name=test2", "--output=json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var changes []store.Change
+		if err := json.Unmarshal([]byte(out), &changes); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(changes) != 1 || changes[0].Table != "accounts" || changes[0].Op != store.ChangeOpUpdate {
+			t.Fatalf("expected one accounts update but got %v", changes)
+		}
+
+		diff := changes[0].Diff()
+		if len(diff) != 1 || diff[0].Column != "name" || diff[0].New != "test2" {
+			t.Fatalf("expected name diff but got %v", diff)
+		}
+
+		var name string
+		if err := db.QueryRowContext(ctx, "SELECT name FROM accounts WHERE guid=?", "2").Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		if name != "test1" {
+			t.Fatalf("expected dry run to leave name test1 but got %s", name)
+		}
+	})
+	t.Run("locked by gnucash", func(t *testing.T) {
+		ctx := context.Background()
+		db, cleanup := newTestingDB(ctx, t)
+		defer cleanup()
+
+		insertTestingAccount(ctx, db, t, "2", "test1", "EXPENSE", "EXPENSESGUID")
+		if _, err := db.ExecContext(ctx, "INSERT INTO gnclock (Hostname, PID) VALUES (?, ?)", "desktop", 1234); err != nil {
+			t.Fatal(err)
+		}
+
+		c := &cli{db: db}
+		_, err := executeCommand(updateAccountCmd(c), "expenses:test1", "--name=test2")
+		if !errors.Is(err, ErrBookLocked) {
+			t.Fatalf("expected ErrBookLocked but received %v", err)
+		}
+
+		c.force = true
+		if _, err := executeCommand(updateAccountCmd(c), "expenses:test1", "--name=test2", "--output=json"); err != nil {
+			t.Fatal(err)
+		}
+
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM gnclock").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatalf("expected only the gnucash lock to remain but found %d locks", count)
+		}
+	})
+	t.Run("unsupported schema", func(t *testing.T) {
+		ctx := context.Background()
+		db, cleanup := newTestingDB(ctx, t)
+		defer cleanup()
+
+		insertTestingAccount(ctx, db, t, "2", "test1", "EXPENSE", "EXPENSESGUID")
+
+		schema := &store.Schema{Versions: map[string]int64{
+			"Gnucash":        5000000,
+			"Gnucash-Resave": 19920,
+			"accounts":       1,
+			"commodities":    1,
+			"slots":          4,
+			"splits":         6,
+			"transactions":   4,
+		}}
+		c := &cli{db: db, schema: schema}
+		_, err := executeCommand(updateAccountCmd(c), "expenses:test1", "--name=test2")
+		if !errors.Is(err, store.ErrSchemaUnsupported) {
+			t.Fatalf("expected ErrSchemaUnsupported but received %v", err)
+		}
+
+		schema.Versions["splits"] = 5
+		if _, err := executeCommand(updateAccountCmd(c), "expenses:test1", "--name=test2", "--output=json"); err != nil {
+			t.Fatal(err)
+		}
+
+		schema.Versions["Gnucash-Resave"] = 6000000
+		_, err = executeCommand(updateAccountCmd(c), "expenses:test2", "--name=test3")
+		if !errors.Is(err, store.ErrSchemaUnsupported) {
+			t.Fatalf("expected ErrSchemaUnsupported for a newer Gnucash-Resave but received %v", err)
+		}
+	})
+}
+
+func TestRegisterAccountCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingAccount(ctx, db, t, "DININGGUID", "Dining", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-04-30", "Opening", "BANKGUID", "ROOTGUID", 100000)
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX3", "2024-05-03", "Dinner", "DININGGUID", "BANKGUID", 6000)
+	if _, err := db.ExecContext(ctx,
+		"INSERT INTO splits (guid, tx_guid, account_guid, memo, action, reconcile_state, value_num, value_denom, quantity_num, quantity_denom) VALUES ('TX3-2', 'TX3', 'GROCERIESGUID', '', '', 'n', 1000, 100, 1000, 100)",
+	); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE splits SET value_num=-7000, quantity_num=-7000 WHERE guid='TX3-1'"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cli{db: db}
+	if _, err := executeCommand(registerAccountCmd(c), "assets:bank"); !errors.Is(err, ErrAccountDoesNotExist) {
+		t.Fatalf("expected ErrAccountDoesNotExist but got %v", err)
+	}
+
+	out, err := executeCommand(registerAccountCmd(c), "bank", "--from=2024-05-01", "--output=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []*store.RegisterEntry
+	if err := json.Unmarshal([]byte(out), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 register entries but got %d", len(entries))
+	}
+
+	if entries[0].CounterAccount != "Expenses:Groceries" || entries[0].AmountNum != -4210 || entries[0].BalanceNum != 95790 {
+		t.Fatalf("unexpected first entry %+v", entries[0])
+	}
+	if entries[1].CounterAccount != store.SplitTransactionAccount || entries[1].AmountNum != -7000 || entries[1].BalanceNum != 88790 {
+		t.Fatalf("unexpected second entry %+v", entries[1])
+	}
+
+	out, err = executeCommand(registerAccountCmd(c), "bank", "--filter=account = expenses:dining", "--output=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(out), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].TXGUID != "TX3" || entries[0].BalanceNum != 88790 {
+		t.Fatalf("expected the filtered register to only show TX3 with the account balance but got %s", out)
+	}
 }
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"gt/internal/backup"
+	"gt/internal/journal"
+	"gt/internal/render"
+	"gt/internal/store"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var ErrBackupsDisabled = errors.New("backups are disabled, gnucash_db_file is not set")
+
+func backupCmd(cli *cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "backup",
+		Short: "Manage backups of the book",
+		Long: `Manage backups of the book.
+
+A backup of the book is taken before every command that writes to it.
+Backups are kept in backup_dir, which defaults to a directory next to
+the book, and only the newest backup_keep (default 10) are kept.`,
+	}
+	cmd.AddCommand(listBackupCmd(cli))
+	cmd.AddCommand(restoreBackupCmd(cli))
+	return cmd
+}
+
+func listBackupCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		output string
+	}
+	var cmd = &cobra.Command{
+		Use:   "list",
+		Short: "List backups",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cli.config.GnucashDBFile == "" {
+				return ErrBackupsDisabled
+			}
+
+			backups, err := backup.List(cli.backupDir(), cli.config.GnucashDBFile)
+			if err != nil {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			return r.Render(cmd.OutOrStdout(), backups)
+		},
+	}
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	return cmd
+}
+
+func restoreBackupCmd(cli *cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:         "restore [id]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Restore the book from a backup",
+		Args:        cobra.ExactArgs(1),
+		Long: `Restore the book from a backup listed by gt backup list.
+
+The current book is backed up before it is overwritten so a restore
+can itself be reverted. The restore is recorded in the history and
+operations performed before it can no longer be undone.`,
+		Example: `  gt backup restore 20240531T101500.000`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cli.config.GnucashDBFile == "" {
+				return ErrBackupsDisabled
+			}
+
+			if cli.readOnly {
+				return ErrReadOnly
+			}
+
+			b, err := backup.Get(cli.backupDir(), cli.config.GnucashDBFile, args[0])
+			if err != nil {
+				return err
+			}
+
+			if cli.dryRun {
+				fmt.Fprintf(cmd.OutOrStdout(), "would restore %s from %s\n", cli.config.GnucashDBFile, b.Path)
+				return nil
+			}
+
+			if cli.confirm {
+				ok, err := confirm(cmd, fmt.Sprintf("Overwrite %s with backup %s?", cli.config.GnucashDBFile, b.ID))
+				if err != nil || !ok {
+					return err
+				}
+			}
+
+			if err := cli.checkLock(cmd); err != nil {
+				return err
+			}
+
+			// Rotate only once restored as rotation may remove the backup
+			// being restored.
+			if err := cli.takeBackup(cmd); err != nil {
+				return err
+			}
+
+			unlock, err := cli.lock(cmd)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+
+			// The backup overwrites the gnclock table too, so the holders
+			// of the lock, including gt, are written back once restored.
+			s := store.NewStore(cli.db)
+			locks, err := s.Locks(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			if err := backup.Restore(cmd.Context(), cli.db, b); err != nil {
+				return err
+			}
+
+			if err := s.ReplaceLocks(cmd.Context(), locks); err != nil {
+				return err
+			}
+
+			if j := cli.journal(); j != nil {
+				entry := &journal.Entry{Time: time.Now().UTC().Truncate(time.Second), Command: commandLine(), Restore: b.ID}
+				if err := j.Append(entry); err != nil {
+					return fmt.Errorf("book restored but not journaled: %w", err)
+				}
+			}
+
+			if err := cli.rotateBackups(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "restored %s from %s\n", cli.config.GnucashDBFile, b.Path)
+			return nil
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"gt/internal/backup"
+	"gt/internal/journal"
+	"os"
+	"testing"
+)
+
+func TestBackupCmd(t *testing.T) {
+	ctx := context.Background()
+
+	f, _ := os.CreateTemp("", "testdb-*.sqlite")
+	dsn := f.Name()
+	f.Close()
+	defer os.Remove(dsn)
+	defer os.Remove(journal.Path(dsn))
+
+	backupDir, err := os.MkdirTemp("", "backups-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(backupDir)
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = createTestingTables(ctx, db, t); err != nil {
+		t.Fatal(err)
+	}
+	insertTestingAccount(ctx, db, t, "2", "test1", "EXPENSE", "EXPENSESGUID")
+
+	c := &cli{db: db, config: config{GnucashDBFile: dsn, BackupDir: backupDir, BackupKeep: 2}}
+	for _, name := range []string{"test2", "test3", "test4"} {
+		if _, err := executeCommand(updateAccountCmd(c), "2", "--name="+name, "--output=json"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := executeCommand(backupCmd(c), "list", "--output=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var backups []*backup.Backup
+	if err := json.Unmarshal([]byte(out), &backups); err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups but got %d", len(backups))
+	}
+
+	// gnucash opened the book after the backups were taken, its lock must
+	// survive the restore.
+	if _, err := db.ExecContext(ctx, "INSERT INTO gnclock (Hostname, PID) VALUES (?, ?)", "desktop", 1234); err != nil {
+		t.Fatal(err)
+	}
+	c.force = true
+
+	// The oldest backup kept was taken before renaming test2 to test3.
+	if _, err := executeCommand(backupCmd(c), "restore", backups[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM gnclock WHERE Hostname=?", "desktop").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected the gnucash lock to be kept but got %d", count)
+	}
+
+	// Operations performed before the restore can no longer be undone.
+	if _, err := executeCommand(undoCmd(c)); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("expected ErrNothingToUndo but got %v", err)
+	}
+	if _, err := executeCommand(undoCmd(c), "1"); !errors.Is(err, ErrUndoRestored) {
+		t.Fatalf("expected ErrUndoRestored but got %v", err)
+	}
+
+	var name string
+	if err := db.QueryRowContext(ctx, "SELECT name FROM accounts WHERE guid=?", "2").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "test2" {
+		t.Fatalf("expected restored name test2 but got %s", name)
+	}
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"gt/internal/render"
+	"gt/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+var ErrCheckFailed = errors.New("book check found errors")
+
+func checkCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		output string
+	}
+	var cmd = &cobra.Command{
+		Use:   "check",
+		Short: "Check the book for integrity problems",
+		Long: `Check the book for integrity problems.
+
+Errors are problems gnucash or gt can not handle correctly: unbalanced
+transactions, splits that reference missing accounts or transactions,
+accounts with missing parents or parent cycles, split quantities that
+differ from the value when the account is in the transaction currency
+and dates that can not be parsed. Warnings are transactions without
+splits, splits posted to placeholder accounts and accounts sharing a
+name with a sibling.
+
+check exits non-zero when it finds errors.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s := store.NewStore(cli.db)
+			issues, err := s.Check(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			if err := r.Render(cmd.OutOrStdout(), issues); err != nil {
+				return err
+			}
+
+			errorCount := 0
+			for _, issue := range issues {
+				if issue.Severity == store.SeverityError {
+					errorCount++
+				}
+			}
+			if errorCount > 0 {
+				return fmt.Errorf("%w: %d errors, %d warnings", ErrCheckFailed, errorCount, len(issues)-errorCount)
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	return cmd
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gt/internal/store"
+	"strings"
+	"testing"
+)
+
+func TestCheckCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+
+	c := &cli{db: db}
+	out, err := executeCommand(checkCmd(c), "--output=json")
+	if err != nil {
+		t.Fatalf("expected a clean book but got %v: %s", err, out)
+	}
+
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-03", "Coles", "GROCERIESGUID", "BANKGUID", 1000)
+	insertTestingAccount(ctx, db, t, "GROCERIES2GUID", "groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingAccount(ctx, db, t, "ORPHANGUID", "Orphan", "EXPENSE", "MISSINGGUID")
+	insertTestingAccount(ctx, db, t, "LOOPAGUID", "LoopA", "EXPENSE", "LOOPBGUID")
+	insertTestingAccount(ctx, db, t, "LOOPBGUID", "LoopB", "EXPENSE", "LOOPAGUID")
+	insertTestingAccount(ctx, db, t, "LOOPCHILDGUID", "LoopChild", "EXPENSE", "LOOPAGUID")
+	for _, q := range []string{
+		"UPDATE splits SET value_num=900, quantity_num=900 WHERE guid='TX2-0'",
+		"UPDATE splits SET quantity_num=1 WHERE guid='TX1-0'",
+		"UPDATE splits SET account_guid='MISSINGGUID' WHERE guid='TX2-1'",
+		"UPDATE accounts SET placeholder=1 WHERE guid='GROCERIESGUID'",
+		"UPDATE transactions SET post_date='02/05/2024' WHERE guid='TX1'",
+		"INSERT INTO transactions (guid, currency_guid, num, description) VALUES ('TX3', 'AUDGUID', '', 'Empty')",
+	} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err = executeCommand(checkCmd(c), "--output=json")
+	if !errors.Is(err, ErrCheckFailed) {
+		t.Fatalf("expected ErrCheckFailed but got %v", err)
+	}
+
+	// The error cobra prints follows the rendered issues.
+	var issues []store.Issue
+	if err := json.NewDecoder(strings.NewReader(out)).Decode(&issues); err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string)
+	for _, issue := range issues {
+		got[issue.Kind+":"+issue.GUID] = string(issue.Severity)
+	}
+	for key, severity := range map[string]string{
+		store.IssueUnbalancedTransaction + ":TX2":          "error",
+		store.IssueValueQuantityMismatch + ":TX1-0":        "error",
+		store.IssueSplitMissingAccount + ":TX2-1":          "error",
+		store.IssueAccountMissingParent + ":ORPHANGUID":    "error",
+		store.IssueInvalidDate + ":TX1":                    "error",
+		store.IssuePlaceholderSplit + ":TX1-0":             "warning",
+		store.IssueDuplicateAccountName + ":GROCERIESGUID": "warning",
+		store.IssueEmptyTransaction + ":TX3":               "warning",
+		store.IssueAccountParentCycle + ":LOOPAGUID":       "error",
+		store.IssueAccountParentCycle + ":LOOPBGUID":       "error",
+		store.IssueAccountParentCycle + ":LOOPCHILDGUID":   "",
+	} {
+		if got[key] != severity {
+			t.Errorf("expected %s %s but got %q", severity, key, got[key])
+		}
+	}
+}
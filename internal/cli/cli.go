@@ -1,29 +1,54 @@
 package cli
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"gt/internal/backup"
+	"gt/internal/filter"
+	"gt/internal/journal"
+	"gt/internal/render"
+	"gt/internal/store"
+	"io"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	ErrTransactionMissing   = errors.New("transaction guid missing")
-	ErrTransactionNotFound  = errors.New("transaction not found")
-	ErrAccountDoesNotExist  = errors.New("account does not exist")
-	ErrAccountMissingParent = errors.New("account missing parent")
-	ErrAccountMissing       = errors.New("account name or guid missing")
-	ErrAccountAlreadyExists = errors.New("account already exists")
+	ErrTransactionMissing    = errors.New("transaction guid missing")
+	ErrTransactionNotFound   = errors.New("transaction not found")
+	ErrTransactionUnbalanced = errors.New("transaction does not balance")
+	ErrSplitNotFound         = errors.New("split not found")
+	ErrSplitReconciled       = errors.New("split is reconciled")
+	ErrTransactionEdit       = errors.New("invalid transaction")
+	ErrAccountDoesNotExist   = errors.New("account does not exist")
+	ErrAccountMissingParent  = errors.New("account missing parent")
+	ErrAccountMissing        = errors.New("account name or guid missing")
+	ErrAccountAlreadyExists  = errors.New("account already exists")
+	ErrBookLocked            = errors.New("book is locked")
+	ErrReadOnly              = errors.New("book is opened read-only")
 )
 
+// annotationWrite marks commands that write to the book so they can be
+// refused up front in read-only mode.
+const annotationWrite = "gt:write"
+
 var (
 	FlagsUsageOutput           = "Output format (json, table)"
 	FlagsUsageIncludeTotals    = "Include account totals when rendering table"
 	FlagsUsageAccountShortName = "Output accounts short name"
+	FlagsUsageFilter           = `Filter expression, e.g. 'account ~ "expenses:dining*" and amount > 50 and date in 2024-Q2'`
+
+	FlagsUsageMatchWindow        = "Days a matching transaction may be posted before or after a statement line"
+	FlagsUsageMatchMinSimilarity = "Share of description words from which a match posted on another day is confident"
 )
 
 func accountError(err error) error {
@@ -35,21 +60,370 @@ func accountError(err error) error {
 	}
 }
 
+// getAccount returns the account identified by guidOrName which is either an
+// account guid or a full account name (e.g. expenses:groceries).
+func getAccount(ctx context.Context, s *store.Store, guidOrName string) (*store.Account, error) {
+	account, err := s.Accounts.Get(ctx, guidOrName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			account, err = s.Accounts.Get(ctx, guidOrName, []store.AccountsOptFunc{store.WithAccountTree(true)}...)
+			if err != nil {
+				return nil, accountError(err)
+			}
+		} else {
+			return nil, accountError(err)
+		}
+	}
+	return account, nil
+}
+
+// getTransaction returns the transaction identified by guid.
+func getTransaction(ctx context.Context, s *store.Store, guid string) (*store.Transaction, error) {
+	transaction, err := s.Transactions.Get(ctx, guid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// applyFilter adds the filter expression, if any, to q.
+func applyFilter(ctx context.Context, s *store.Store, q *store.TransactionQuery, expr string) error {
+	if expr == "" {
+		return nil
+	}
+
+	tree, err := s.AccountTree(ctx)
+	if err != nil {
+		return err
+	}
+	return filter.Apply(q, expr, tree)
+}
+
+// filterRegister returns the entries of the register of account whose
+// transaction matches the filter expression, keeping the entries keep
+// reports true for regardless. Without a filter entries are returned as is.
+func filterRegister(ctx context.Context, s *store.Store, account *store.Account, entries []*store.RegisterEntry, expr string, keep func(*store.RegisterEntry) bool) ([]*store.RegisterEntry, error) {
+	if expr == "" {
+		return entries, nil
+	}
+
+	q := store.NewTransactionQuery().WhereAccount(account.GUID, false)
+	if err := applyFilter(ctx, s, q, expr); err != nil {
+		return nil, err
+	}
+	transactions, err := s.Transactions.All(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]bool, len(transactions))
+	for _, transaction := range transactions {
+		matched[transaction.GUID] = true
+	}
+
+	filtered := []*store.RegisterEntry{}
+	for _, entry := range entries {
+		if matched[entry.TXGUID] || (keep != nil && keep(entry)) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered, nil
+}
+
+// getImbalanceAccount returns the top level Imbalance-<currency> account
+// gnucash uses to hold unbalanced amounts, creating it if it does not exist.
+func getImbalanceAccount(ctx context.Context, s *store.Store, commodity *store.Commodity) (*store.Account, error) {
+	return getTopLevelAccount(ctx, s, fmt.Sprintf("Imbalance-%s", commodity.Mnemonic), "BANK", commodity)
+}
+
+func getTopLevelAccount(ctx context.Context, s *store.Store, name, accountType string, commodity *store.Commodity) (*store.Account, error) {
+	account, err := s.Accounts.Get(ctx, name, []store.AccountsOptFunc{store.WithAccountTree(true)}...)
+	if err == nil {
+		return account, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	q := store.NewAccountQuery().Where("account_type=? AND name=? AND parent_guid IS NULL", "ROOT", "Root Account")
+	roots, err := s.Accounts.All(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) == 0 {
+		return nil, ErrAccountMissingParent
+	}
+
+	account = &store.Account{
+		Name:          name,
+		FullName:      name,
+		AccountType:   accountType,
+		CommodityGUID: &commodity.GUID,
+		CommoditySCU:  commodity.Fraction,
+		ParentGUID:    &roots[0].GUID,
+	}
+	if err := s.Accounts.Insert(ctx, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// write runs fn in one database transaction. With --dry-run the rows fn
+// changed are rendered and rolled back, with --confirm they are rendered and
+// only committed once the user agrees. It reports whether the changes were
+// committed.
+func (c *cli) write(cmd *cobra.Command, output string, fn func(*store.Store) error) (bool, error) {
+	return c.writeEntry(cmd, output, &journal.Entry{}, fn)
+}
+
+// writeEntry is write with the journal entry recorded for the changes, once
+// committed, given by the caller.
+func (c *cli) writeEntry(cmd *cobra.Command, output string, entry *journal.Entry, fn func(*store.Store) error) (bool, error) {
+	if c.readOnly {
+		return false, ErrReadOnly
+	}
+
+	// The schema is only unknown when the command was not run through the root
+	// command (i.e. in tests).
+	if c.schema != nil {
+		if err := c.schema.CheckWritable(); err != nil {
+			return false, fmt.Errorf("refusing to write: %w", err)
+		}
+	}
+
+	if !c.dryRun {
+		if err := c.checkLock(cmd); err != nil {
+			return false, err
+		}
+
+		// Backup before locking so backups do not hold our lock.
+		if err := c.backup(cmd); err != nil {
+			return false, err
+		}
+
+		unlock, err := c.lock(cmd)
+		if err != nil {
+			return false, err
+		}
+		defer unlock()
+	}
+
+	committed := false
+	s := store.NewStore(c.db)
+	err := s.ExecTx(cmd.Context(), fn, store.WithBeforeCommit(func(changes []store.Change) (bool, error) {
+		entry.Changes = changes
+		if !c.dryRun && !c.confirm {
+			committed = true
+			return true, nil
+		}
+
+		r, err := render.New(output)
+		if err != nil {
+			return false, err
+		}
+		if err := r.Render(cmd.OutOrStdout(), changes); err != nil {
+			return false, err
+		}
+
+		if c.dryRun || len(changes) == 0 {
+			return false, nil
+		}
+
+		committed, err = confirm(cmd, fmt.Sprintf("Apply %d changes?", len(changes)))
+		return committed, err
+	}))
+	if err != nil || !committed || len(entry.Changes) == 0 {
+		return committed, err
+	}
+
+	if j := c.journal(); j != nil {
+		entry.Time = time.Now().UTC().Truncate(time.Second)
+		entry.Command = commandLine()
+		if err := j.Append(entry); err != nil {
+			return committed, fmt.Errorf("changes saved but not journaled: %w", err)
+		}
+	}
+
+	return committed, nil
+}
+
+// checkLock refuses to continue when the book is locked by gnucash, unless
+// --force is set.
+func (c *cli) checkLock(cmd *cobra.Command) error {
+	s := store.NewStore(c.db)
+	locks, err := s.Locks(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	if len(locks) > 0 && !c.force {
+		holders := make([]string, len(locks))
+		for i, l := range locks {
+			holders[i] = fmt.Sprintf("%s (pid %d)", l.Hostname, l.PID)
+		}
+		return fmt.Errorf("%w by %s, close gnucash or use --force", ErrBookLocked, strings.Join(holders, ", "))
+	}
+
+	return nil
+}
+
+// lock locks the book, so gnucash warns when it is opened concurrently,
+// until unlock is called.
+func (c *cli) lock(cmd *cobra.Command) (unlock func(), err error) {
+	s := store.NewStore(c.db)
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+
+	lock := store.Lock{Hostname: hostname, PID: int64(os.Getpid())}
+	if err := s.Lock(cmd.Context(), lock); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if err := s.Unlock(context.Background(), lock); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "failed to unlock book: %s\n", err)
+		}
+	}, nil
+}
+
+// backupDir returns the directory backups of the book are kept in.
+func (c *cli) backupDir() string {
+	if c.config.BackupDir != "" {
+		return c.config.BackupDir
+	}
+	return backup.Dir(c.config.GnucashDBFile)
+}
+
+// backup takes a backup of the book and removes the oldest backups beyond
+// backup_keep.
+func (c *cli) backup(cmd *cobra.Command) error {
+	if err := c.takeBackup(cmd); err != nil {
+		return err
+	}
+	return c.rotateBackups()
+}
+
+func (c *cli) takeBackup(cmd *cobra.Command) error {
+	if c.config.GnucashDBFile == "" || c.config.BackupKeep <= 0 {
+		return nil
+	}
+
+	b, err := backup.Create(cmd.Context(), c.db, c.backupDir(), c.config.GnucashDBFile)
+	if err != nil {
+		return fmt.Errorf("failed to backup book: %w", err)
+	}
+
+	if c.debug {
+		fmt.Fprintf(cmd.ErrOrStderr(), "backup %s written to %s\n", b.ID, b.Path)
+	}
+
+	return nil
+}
+
+func (c *cli) rotateBackups() error {
+	if c.config.GnucashDBFile == "" || c.config.BackupKeep <= 0 {
+		return nil
+	}
+	return backup.Rotate(c.backupDir(), c.config.GnucashDBFile, c.config.BackupKeep)
+}
+
+// journal returns the change journal kept next to the book, or nil when
+// there is no book file to keep it next to.
+func (c *cli) journal() *journal.Journal {
+	if c.config.GnucashDBFile == "" {
+		return nil
+	}
+	return journal.New(journal.Path(c.config.GnucashDBFile))
+}
+
+func commandLine() string {
+	args := make([]string, len(os.Args))
+	for i, arg := range os.Args {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		args[i] = arg
+	}
+	return strings.Join(args, " ")
+}
+
+// readLine reads a line from r one byte at a time so that no input past the
+// line is consumed from a shared reader such as stdin.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				return string(line), nil
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			return string(line), err
+		}
+	}
+}
+
+// confirm asks the user a yes/no question on the command's input.
+func confirm(cmd *cobra.Command, question string) (bool, error) {
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s [y/N] ", question)
+	answer, err := readLine(cmd.InOrStdin())
+	if err != nil && answer == "" {
+		return false, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
 type cli struct {
 	debug      bool
 	configFile string
+	dryRun     bool
+	confirm    bool
+	force      bool
+	readOnly   bool
 	initOnce   sync.Once
 	errOnce    error
 	config     config
 	db         *sql.DB
+	schema     *store.Schema
 }
 
 type config struct {
 	GnucashDBFile string `json:"gnucash_db_file"`
+	RulesFile     string `json:"rules_file"`
+	ReadOnly      bool   `json:"read_only"`
+	// BackupDir defaults to <gnucash_db_file>.gt-backups.
+	BackupDir string `json:"backup_dir"`
+	// BackupKeep is the number of backups kept, 0 disables backups.
+	BackupKeep int `json:"backup_keep"`
 }
 
-func (c *cli) setup() error {
-	return c.init()
+func (c *cli) setup(cmd *cobra.Command) error {
+	if err := c.init(); err != nil {
+		return err
+	}
+
+	if c.readOnly && cmd.Annotations[annotationWrite] != "" {
+		return fmt.Errorf("%w, %s writes to the book", ErrReadOnly, cmd.CommandPath())
+	}
+
+	if err := c.schema.CheckReadable(); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", err)
+	}
+
+	return nil
 }
 
 func (c *cli) init() error {
@@ -70,6 +444,8 @@ func (c *cli) initContext() error {
 	}
 	c.config = config{
 		GnucashDBFile: path.Join(homeDir, ".gnucash.sql.gnucash"),
+		RulesFile:     path.Join(homeDir, ".gt-rules.json"),
+		BackupKeep:    10,
 	}
 
 	if c.configFile != "" {
@@ -82,7 +458,20 @@ func (c *cli) initContext() error {
 		}
 	}
 
-	c.db, err = sql.Open("sqlite3", c.config.GnucashDBFile)
+	c.readOnly = c.readOnly || c.config.ReadOnly
+
+	dsn := c.config.GnucashDBFile
+	if c.readOnly {
+		dsn = store.ReadOnlyDSN(dsn)
+	}
+
+	c.db, err = sql.Open("sqlite3", dsn)
+	if err != nil {
+		return err
+	}
+
+	s := store.NewStore(c.db)
+	c.schema, err = s.Schema(context.Background())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"gt/internal/render"
+	"gt/internal/store"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// reversedBySlot links a transaction to the transaction reversing it.
+const reversedBySlot = "reversed-by"
+
+var ErrTransactionReversed = errors.New("transaction is already reversed")
+
+func reverseTransactionCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		date      string
+		output    string
+		shortName bool
+	}
+	var cmd = &cobra.Command{
+		Use:         "reverse [guid]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Reverse a transaction",
+		Long: `Reverse a transaction.
+
+A copy of the transaction is posted on --date, today if not given, with
+the amounts of its splits negated and their reconcile state set to n.
+As gnucash does, the reversed transaction is linked to the reversing one
+by its reversed-by slot and can only be reversed once.`,
+		Example: `  gt transaction reverse <guid> --date 2024-07-01`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			guid := args[0]
+
+			postDate := time.Now()
+			if flags.date != "" {
+				var err error
+				if postDate, err = time.Parse("2006-01-02", flags.date); err != nil {
+					return err
+				}
+			}
+
+			var reversal *store.Transaction
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				transaction, err := getTransaction(cmd.Context(), txStore, guid)
+				if err != nil {
+					return err
+				}
+
+				for _, check := range []struct {
+					slot string
+					err  error
+				}{
+					{reversedBySlot, ErrTransactionReversed},
+					{voidReasonSlot, ErrTransactionVoided},
+				} {
+					slot, err := txStore.Slots.Get(cmd.Context(), transaction.GUID, check.slot)
+					if err != nil {
+						return err
+					}
+					if slot != nil {
+						return fmt.Errorf("%w: %s", check.err, transaction.GUID)
+					}
+				}
+
+				reversal = copyTransaction(transaction, postDate, time.Now().UTC().Truncate(time.Second))
+				for _, split := range reversal.Splits {
+					split.ValueNum, split.QuantityNum = -split.ValueNum, -split.QuantityNum
+				}
+				if err := txStore.Transactions.Insert(cmd.Context(), reversal); err != nil {
+					return err
+				}
+
+				return txStore.Slots.Insert(cmd.Context(), &store.Slot{
+					ObjGUID:  transaction.GUID,
+					Name:     reversedBySlot,
+					SlotType: store.SlotTypeGUID,
+					GUIDVal:  &reversal.GUID,
+				})
+			})
+			if err != nil || !committed {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			renderOpts := []render.RendererOptsFunc{render.WithAccountShortName(flags.shortName)}
+			return r.Render(cmd.OutOrStdout(), reversal, renderOpts...)
+		},
+	}
+	cmd.Flags().StringVar(&flags.date, "date", "", "Post date of the reversing transaction (YYYY-MM-DD), today if not given")
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
+	return cmd
+}
+
+func duplicateTransactionCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		date      string
+		count     int
+		every     string
+		output    string
+		shortName bool
+	}
+	var cmd = &cobra.Command{
+		Use:         "duplicate [guid]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Copy a transaction to new dates",
+		Long: `Copy a transaction to new dates.
+
+The transaction is copied with new guids and today's enter date and
+posted on --date. With --count it is copied that many times, each copy
+posted one --every later than the one before. Monthly and yearly copies
+fall on the last day of months shorter than --date's day, so copies of
+2024-01-31 are posted on 2024-02-29, 2024-03-31 and so on. The splits of
+the copies are not reconciled. Voided transactions can not be copied.`,
+		Example: `  gt transaction duplicate <guid> --date 2024-06-01
+  gt transaction duplicate <guid> --date 2024-01-31 --count 12 --every month`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			guid := args[0]
+
+			date, err := time.Parse("2006-01-02", flags.date)
+			if err != nil {
+				return err
+			}
+			if flags.count < 1 {
+				return fmt.Errorf("invalid count %d, expected at least 1", flags.count)
+			}
+			if _, err := addPeriod(date, flags.every, 0); err != nil {
+				return err
+			}
+
+			var copies []*store.Transaction
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				transaction, err := getTransaction(cmd.Context(), txStore, guid)
+				if err != nil {
+					return err
+				}
+
+				voided, err := txStore.Slots.Get(cmd.Context(), transaction.GUID, voidReasonSlot)
+				if err != nil {
+					return err
+				}
+				if voided != nil {
+					return fmt.Errorf("%w: %s", ErrTransactionVoided, transaction.GUID)
+				}
+
+				copies = make([]*store.Transaction, flags.count)
+				enterDate := time.Now().UTC().Truncate(time.Second)
+				for idx := range copies {
+					postDate, err := addPeriod(date, flags.every, idx)
+					if err != nil {
+						return err
+					}
+					copies[idx] = copyTransaction(transaction, postDate, enterDate)
+					if err := txStore.Transactions.Insert(cmd.Context(), copies[idx]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil || !committed {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			renderOpts := []render.RendererOptsFunc{render.WithAccountShortName(flags.shortName)}
+			return r.Render(cmd.OutOrStdout(), copies, renderOpts...)
+		},
+	}
+	cmd.Flags().StringVar(&flags.date, "date", "", "Post date of the (first) copy (YYYY-MM-DD)")
+	cmd.Flags().IntVar(&flags.count, "count", 1, "Number of copies")
+	cmd.Flags().StringVar(&flags.every, "every", "month", "Time between copies (day, week, month or year)")
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
+	cmd.MarkFlagRequired("date")
+	return cmd
+}
+
+// copyTransaction returns a copy of transaction and its splits without
+// guids, posted on the day of postDate and entered at enterDate. The splits
+// of the copy are not reconciled and not in a lot.
+func copyTransaction(transaction *store.Transaction, postDate, enterDate time.Time) *store.Transaction {
+	postDate = store.DatePost(postDate)
+
+	c := *transaction
+	c.GUID = ""
+	c.PostDate = &postDate
+	c.EnterDate = &enterDate
+	c.Splits = make([]*store.Split, len(transaction.Splits))
+	for idx, split := range transaction.Splits {
+		s := *split
+		s.GUID = ""
+		s.TXGUID = ""
+		s.ReconcileState = "n"
+		s.ReconcileDate = nil
+		s.LogGUID = nil
+		c.Splits[idx] = &s
+	}
+	return &c
+}
+
+// addPeriod returns date moved n periods of every (day, week, month or
+// year) later. Months and years that are shorter than the day of date end
+// on their last day.
+func addPeriod(date time.Time, every string, n int) (time.Time, error) {
+	var months int
+	switch every {
+	case "day":
+		return date.AddDate(0, 0, n), nil
+	case "week":
+		return date.AddDate(0, 0, 7*n), nil
+	case "month":
+		months = n
+	case "year":
+		months = 12 * n
+	default:
+		return time.Time{}, fmt.Errorf("invalid period %q, expected one of day, week, month or year", every)
+	}
+
+	first := time.Date(date.Year(), date.Month()+time.Month(months), 1, date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(date.Day(), last)-1), nil
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReverseTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+
+	if _, err := db.ExecContext(ctx, "UPDATE splits SET reconcile_state = 'y' WHERE tx_guid = 'TX1'"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cli{db: db}
+
+	if _, err := executeCommand(reverseTransactionCmd(c), "TX1", "--date", "2024-07-01"); err != nil {
+		t.Fatal(err)
+	}
+
+	var reversal string
+	if err := db.QueryRowContext(ctx, "SELECT guid_val FROM slots WHERE obj_guid = 'TX1' AND name = ?", reversedBySlot).Scan(&reversal); err != nil {
+		t.Fatal(err)
+	}
+
+	var postDate, description string
+	if err := db.QueryRowContext(ctx, "SELECT post_date, description FROM transactions WHERE guid = ?", reversal).Scan(&postDate, &description); err != nil {
+		t.Fatal(err)
+	}
+	if postDate != "2024-07-01 10:59:00" || description != "Woolworths" {
+		t.Fatalf("expected reversal posted 2024-07-01 10:59:00 as Woolworths but got %s %s", postDate, description)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT account_guid, reconcile_state, value_num, quantity_num FROM splits WHERE tx_guid = ? ORDER BY value_num", reversal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var account, state string
+		var value, quantity int64
+		if err := rows.Scan(&account, &state, &value, &quantity); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s %s %d %d", account, state, value, quantity))
+	}
+	expected := `GROCERIESGUID n -4210 -4210
+BANKGUID n 4210 4210`
+	if strings.Join(got, "\n") != expected {
+		t.Fatalf("expected splits\n%s\nbut got\n%s", expected, strings.Join(got, "\n"))
+	}
+
+	_, err = executeCommand(reverseTransactionCmd(c), "TX1")
+	if !errors.Is(err, ErrTransactionReversed) {
+		t.Fatalf("expected ErrTransactionReversed but got %v", err)
+	}
+}
+
+func TestDuplicateTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "RENTGUID", "Rent", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2023-12-31", "Rent", "RENTGUID", "BANKGUID", 150000)
+
+	c := &cli{db: db}
+
+	if _, err := executeCommand(duplicateTransactionCmd(c), "TX1", "--date", "2024-01-31", "--count", "3", "--every", "month"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+SELECT transactions.post_date, COUNT(splits.guid), SUM(splits.value_num > 0)
+FROM transactions JOIN splits ON splits.tx_guid = transactions.guid
+WHERE transactions.guid != 'TX1' AND transactions.description = 'Rent' AND splits.guid NOT LIKE 'TX1%'
+GROUP BY transactions.guid
+ORDER BY transactions.post_date`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var got []string
+	for rows.Next() {
+		var postDate string
+		var splits, debits int
+		if err := rows.Scan(&postDate, &splits, &debits); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s %d %d", postDate, splits, debits))
+	}
+	expected := `2024-01-31 10:59:00 2 1
+2024-02-29 10:59:00 2 1
+2024-03-31 10:59:00 2 1`
+	if strings.Join(got, "\n") != expected {
+		t.Fatalf("expected copies\n%s\nbut got\n%s", expected, strings.Join(got, "\n"))
+	}
+
+	if _, err := executeCommand(duplicateTransactionCmd(c), "TX1", "--date", "2024-01-31", "--every", "fortnight"); err == nil {
+		t.Fatal("expected an error for an invalid period")
+	}
+	if _, err := executeCommand(voidTransactionCmd(c), "TX1", "--reason", "paid twice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand(duplicateTransactionCmd(c), "TX1", "--date", "2024-04-30"); !errors.Is(err, ErrTransactionVoided) {
+		t.Fatalf("expected ErrTransactionVoided but got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"gt/internal/match"
+	"gt/internal/render"
+	"gt/internal/store"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func duplicatesTransactionCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		account       string
+		window        string
+		filter        string
+		minSimilarity float64
+		deleteExtras  bool
+		output        string
+		shortName     bool
+	}
+	var cmd = &cobra.Command{
+		Use:   "duplicates",
+		Short: "Find transactions entered more than once",
+		Long: `Find transactions entered more than once.
+
+Transactions are duplicates when their splits are on the same accounts
+with the same amounts and they are posted within the window of each
+other. With --min-similarity their descriptions must also share that
+many words. Each group is listed with the earliest entered transaction
+first, which is the one --delete-extras keeps while the others are
+deleted with their splits and slots. Reconciled and voided duplicates
+are never deleted, they are reported as skipped instead.`,
+		Example: `  gt transaction duplicates --account expenses:groceries --window 3d
+  gt --dry-run transaction duplicates --window 1d --delete-extras`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			window, err := parseWindow(flags.window)
+			if err != nil {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+			renderOpts := []render.RendererOptsFunc{render.WithAccountShortName(flags.shortName)}
+
+			find := func(s *store.Store) ([]match.DuplicateGroup, error) {
+				q := store.NewTransactionQuery().OrderBy("post_date", false)
+				if flags.account != "" {
+					account, err := getAccount(cmd.Context(), s, flags.account)
+					if err != nil {
+						return nil, err
+					}
+					q.WhereAccount(account.GUID, false)
+				}
+				if err := applyFilter(cmd.Context(), s, q, flags.filter); err != nil {
+					return nil, err
+				}
+
+				transactions, err := s.Transactions.All(cmd.Context(), q)
+				if err != nil {
+					return nil, err
+				}
+				return match.Duplicates(transactions, window, flags.minSimilarity), nil
+			}
+
+			if !flags.deleteExtras {
+				s := store.NewStore(cli.db)
+				groups, err := find(&s)
+				if err != nil {
+					return err
+				}
+				return r.Render(cmd.OutOrStdout(), groups, renderOpts...)
+			}
+
+			// Only --delete-extras writes to the book so the command is not
+			// annotated as writing and read-only mode is checked here, before
+			// anything is rendered.
+			if cli.readOnly {
+				return fmt.Errorf("%w, %s --delete-extras writes to the book", ErrReadOnly, cmd.CommandPath())
+			}
+
+			// The groups are found again in the write transaction so the
+			// transactions deleted are the ones in the book once it is locked.
+			_, err = cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				groups, err := find(txStore)
+				if err != nil {
+					return err
+				}
+				if err := r.Render(cmd.OutOrStdout(), groups, renderOpts...); err != nil {
+					return err
+				}
+
+				for _, group := range groups {
+					for _, transaction := range group.Transactions[1:] {
+						reason, err := keepDuplicate(cmd.Context(), txStore, transaction)
+						if err != nil {
+							return err
+						}
+						if reason != "" {
+							fmt.Fprintf(cmd.ErrOrStderr(), "skipped duplicate %s: %s\n", transaction.GUID, reason)
+							continue
+						}
+
+						if err := txStore.Transactions.Delete(cmd.Context(), transaction); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&flags.account, "account", "", "Only look at transactions with a split on this account (GUID or full name)")
+	cmd.Flags().StringVar(&flags.window, "window", "3d", "How far apart duplicates may be posted (e.g. 3d, 36h)")
+	cmd.Flags().StringVar(&flags.filter, "filter", "", FlagsUsageFilter)
+	cmd.Flags().Float64Var(&flags.minSimilarity, "min-similarity", 0, "Share of description words duplicates must have in common, 0 ignores descriptions")
+	cmd.Flags().BoolVar(&flags.deleteExtras, "delete-extras", false, "Delete every duplicate but the earliest entered")
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
+	return cmd
+}
+
+// keepDuplicate returns why the duplicate transaction must not be deleted,
+// or "" when it may be.
+func keepDuplicate(ctx context.Context, s *store.Store, transaction *store.Transaction) (string, error) {
+	voided, err := s.Slots.Get(ctx, transaction.GUID, voidReasonSlot)
+	if err != nil {
+		return "", err
+	}
+	if voided != nil {
+		return "voided", nil
+	}
+
+	for _, split := range transaction.Splits {
+		if split.ReconcileState == "y" {
+			return "reconciled", nil
+		}
+	}
+	return "", nil
+}
+
+// parseWindow parses a window given in days (e.g. 3d) or as a duration (e.g.
+// 36h).
+func parseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid window: %s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	window, err := time.ParseDuration(s)
+	if err != nil || window < 0 {
+		return 0, fmt.Errorf("invalid window: %s", s)
+	}
+	return window, nil
+}
@@ -0,0 +1,135 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gt/internal/match"
+	"strings"
+	"testing"
+)
+
+func TestDuplicatesTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-04", "WOOLWORTHS 1234", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX3", "2024-05-20", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX4", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 1500)
+
+	// TX2 was entered first so it is the one kept.
+	if _, err := db.ExecContext(ctx, "UPDATE transactions SET enter_date = '2024-05-01 09:00:00' WHERE guid = 'TX2'"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cli{db: db}
+
+	out, err := executeCommand(duplicatesTransactionCmd(c), "--account=expenses:groceries", "--output=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var groups []match.DuplicateGroup
+	if err := json.Unmarshal([]byte(out), &groups); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || len(groups[0].Transactions) != 2 {
+		t.Fatalf("expected one group of two transactions but got %s", out)
+	}
+	if groups[0].Transactions[0].GUID != "TX2" || groups[0].Transactions[1].GUID != "TX1" {
+		t.Fatalf("expected TX2 then TX1 but got %s and %s", groups[0].Transactions[0].GUID, groups[0].Transactions[1].GUID)
+	}
+
+	out, err = executeCommand(duplicatesTransactionCmd(c), "--window=1d", "--output=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(out), &groups); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 0 {
+		t.Fatalf("expected no duplicates within a day but got %s", out)
+	}
+
+	out, err = executeCommand(duplicatesTransactionCmd(c), "--min-similarity=1", "--output=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(out), &groups); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("expected duplicates with similar descriptions but got %s", out)
+	}
+
+	out, err = executeCommand(duplicatesTransactionCmd(&cli{db: db, readOnly: true}), "--delete-extras")
+	if !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly but got %v", err)
+	}
+	if strings.Contains(out, "TX1") {
+		t.Fatalf("expected nothing rendered in read-only mode but got %s", out)
+	}
+
+	if _, err := executeCommand(duplicatesTransactionCmd(c), "--delete-extras"); err != nil {
+		t.Fatal(err)
+	}
+
+	for guid, want := range map[string]int{"TX1": 0, "TX2": 1, "TX3": 1, "TX4": 1} {
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE guid = ?", guid).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Fatalf("expected %d transactions %s but got %d", want, guid, count)
+		}
+	}
+
+	var splits int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM splits WHERE tx_guid = 'TX1'").Scan(&splits); err != nil {
+		t.Fatal(err)
+	}
+	if splits != 0 {
+		t.Fatalf("expected the splits of TX1 to be deleted but got %d", splits)
+	}
+
+	// Reconciled and voided duplicates are kept.
+	insertTestingTransaction(ctx, db, t, "TX5", "2024-05-05", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	if _, err := db.ExecContext(ctx, "UPDATE splits SET reconcile_state = 'y' WHERE guid = 'TX5-1'"); err != nil {
+		t.Fatal(err)
+	}
+	insertTestingTransaction(ctx, db, t, "TX6", "2024-06-01", "Coles", "GROCERIESGUID", "BANKGUID", 990)
+	insertTestingTransaction(ctx, db, t, "TX7", "2024-06-01", "Coles", "GROCERIESGUID", "BANKGUID", 990)
+	if _, err := db.ExecContext(ctx, "UPDATE transactions SET enter_date = '2024-05-31 09:00:00' WHERE guid = 'TX6'"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand(voidTransactionCmd(c), "TX7", "--reason", "entered twice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand(voidTransactionCmd(c), "TX6", "--reason", "entered twice"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err = executeCommand(duplicatesTransactionCmd(c), "--delete-extras")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"skipped duplicate TX5: reconciled", "skipped duplicate TX7: voided"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q but got %s", want, out)
+		}
+	}
+
+	for _, guid := range []string{"TX5", "TX6", "TX7"} {
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE guid = ?", guid).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatalf("expected transaction %s to be kept but got %d", guid, count)
+		}
+	}
+}
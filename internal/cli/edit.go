@@ -0,0 +1,425 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gt/internal/store"
+	"math/big"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const editHelp = `# Edit the transaction below, save and quit the editor to apply it.
+# Lines starting with # are ignored and an unchanged transaction is left
+# alone. Splits are written as
+#
+#   account  amount  ; memo
+#
+# with two or more spaces between the account and the amount, debits
+# positive and credits negative. Splits keep their reconcile state while
+# they stay on the same account. The splits must balance. Delete
+# everything to abort.
+`
+
+func editTransactionCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		output string
+	}
+	var cmd = &cobra.Command{
+		Use:         "edit [guid]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Edit a transaction in $EDITOR",
+		Long: `Edit a transaction in $EDITOR.
+
+The transaction and its splits are written as text and opened in
+$EDITOR (vi when unset). When the edited text does not parse, an
+account does not exist or the splits do not balance the editor is
+opened again with the error at the top. The differences are shown and
+the transaction is updated in one database transaction.`,
+		Example: `  EDITOR=nano gt transaction edit <guid>`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			guid := args[0]
+
+			s := store.NewStore(cli.db)
+			transaction, err := s.Transactions.Get(cmd.Context(), guid)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return ErrTransactionNotFound
+				}
+				return err
+			}
+
+			currency, err := s.Commodities.Get(cmd.Context(), transaction.CurrencyGUID)
+			if err != nil {
+				return err
+			}
+
+			original := formatTransactionText(transaction, currency)
+			text := original
+			var edited *transactionEdit
+			for {
+				text, err = runEditor(cmd, text)
+				if err != nil {
+					return err
+				}
+				if stripComments(text) == "" {
+					fmt.Fprintln(cmd.ErrOrStderr(), "edit aborted")
+					return nil
+				}
+				if stripComments(text) == stripComments(original) {
+					fmt.Fprintln(cmd.ErrOrStderr(), "transaction unchanged")
+					return nil
+				}
+
+				edited, err = parseTransactionText(cmd.Context(), &s, text, currency)
+				if err == nil {
+					break
+				}
+				text = fmt.Sprintf("# error: %s\n%s", err, stripErrors(text))
+			}
+
+			for _, line := range diffLines(strings.Split(stripComments(original), "\n"), strings.Split(stripComments(text), "\n")) {
+				fmt.Fprintln(cmd.ErrOrStderr(), line)
+			}
+
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				transaction, err = txStore.Transactions.Get(cmd.Context(), guid)
+				if err != nil {
+					return err
+				}
+				return applyTransactionEdit(cmd.Context(), txStore, transaction, edited, currency, cli.force)
+			})
+			if err != nil || !committed {
+				return err
+			}
+
+			fmt.Fprintln(cmd.ErrOrStderr(), "transaction updated")
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	return cmd
+}
+
+// transactionEdit is a transaction as parsed from its text form.
+type transactionEdit struct {
+	postDate    time.Time
+	num         string
+	description string
+	splits      []splitEdit
+}
+
+type splitEdit struct {
+	account  *store.Account
+	valueNum int64
+	memo     string
+}
+
+// formatTransactionText returns the text form of transaction that is edited.
+func formatTransactionText(transaction *store.Transaction, currency *store.Commodity) string {
+	var b strings.Builder
+	b.WriteString(editHelp)
+	b.WriteString("\n")
+
+	date := ""
+	if transaction.PostDate != nil {
+		date = transaction.PostDate.Format("2006-01-02")
+	}
+	description := ""
+	if transaction.Description != nil {
+		description = *transaction.Description
+	}
+	fmt.Fprintf(&b, "date: %s\nnum: %s\ndescription: %s\n\n", date, transaction.Num, description)
+
+	width := 0
+	for _, split := range transaction.Splits {
+		width = max(width, len(splitAccountName(split)))
+	}
+	for _, split := range transaction.Splits {
+		line := fmt.Sprintf("%-*s  %s", width, splitAccountName(split), formatAmountText(split.ValueNum, split.ValueDenom, currency))
+		if split.Memo != "" {
+			line += "  ; " + split.Memo
+		}
+		b.WriteString(line + "\n")
+	}
+
+	return b.String()
+}
+
+func splitAccountName(split *store.Split) string {
+	if split.Account == nil || split.Account.FullName == "" {
+		return split.AccountGUID
+	}
+	return split.Account.FullName
+}
+
+// formatAmountText returns valueNum/valueDenom as a decimal with as many
+// places as the currency has.
+func formatAmountText(valueNum, valueDenom int64, currency *store.Commodity) string {
+	if valueDenom == 0 {
+		return "0"
+	}
+	places := len(fmt.Sprint(currency.Fraction)) - 1
+	return big.NewRat(valueNum, valueDenom).FloatString(places)
+}
+
+// shownValueNum returns the value of split at the currency fraction it is
+// shown at, so amounts read back from their text form compare equal to the
+// splits they were shown for.
+func shownValueNum(split *store.Split, currency *store.Commodity) int64 {
+	if split.ValueDenom == 0 {
+		return 0
+	}
+	return store.ConvertNum(split.ValueNum, split.ValueDenom, currency.Fraction)
+}
+
+var (
+	editHeaderRe = regexp.MustCompile(`(?i)^(date|num|description):(?:\s(.*))?$`)
+	editSplitRe  = regexp.MustCompile(`^(.+?)\s{2,}(\S+)(?:\s+;\s?(.*))?$`)
+)
+
+// parseTransactionText parses the text form of a transaction, resolving the
+// accounts of its splits, and checks the splits balance.
+func parseTransactionText(ctx context.Context, s *store.Store, text string, currency *store.Commodity) (*transactionEdit, error) {
+	edit := &transactionEdit{}
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if m := editHeaderRe.FindStringSubmatch(line); m != nil && len(edit.splits) == 0 {
+			key, value := strings.ToLower(m[1]), strings.TrimSpace(m[2])
+			seen[key] = true
+			switch key {
+			case "date":
+				postDate, err := time.Parse("2006-01-02", value)
+				if err != nil {
+					return nil, fmt.Errorf("%w: line %d: date %q is not YYYY-MM-DD", ErrTransactionEdit, n, value)
+				}
+				edit.postDate = store.DatePost(postDate)
+			case "num":
+				edit.num = value
+			case "description":
+				edit.description = value
+			}
+			continue
+		}
+
+		m := editSplitRe.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("%w: line %d: expected account  amount  ; memo", ErrTransactionEdit, n)
+		}
+
+		account, err := getAccount(ctx, s, strings.TrimSpace(m[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: account %s does not exist", ErrTransactionEdit, n, strings.TrimSpace(m[1]))
+		}
+		valueNum, err := store.ParseAmount(m[2], currency.Fraction)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", ErrTransactionEdit, n, err)
+		}
+		edit.splits = append(edit.splits, splitEdit{account: account, valueNum: valueNum, memo: strings.TrimSpace(m[3])})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !seen["date"] {
+		return nil, fmt.Errorf("%w: date is missing", ErrTransactionEdit)
+	}
+
+	if len(edit.splits) == 0 {
+		return nil, fmt.Errorf("%w: transaction has no splits", ErrTransactionUnbalanced)
+	}
+	var balance int64
+	for _, split := range edit.splits {
+		balance += split.valueNum
+	}
+	if balance != 0 {
+		return nil, fmt.Errorf("%w: splits are off by %s", ErrTransactionUnbalanced, formatAmountText(balance, currency.Fraction, currency))
+	}
+
+	return edit, nil
+}
+
+// applyTransactionEdit updates transaction to edit. An edited split takes the
+// place of the first remaining split on the same account, other edited splits
+// are added and the remaining splits removed.
+func applyTransactionEdit(ctx context.Context, s *store.Store, transaction *store.Transaction, edit *transactionEdit, currency *store.Commodity, force bool) error {
+	postDate, description := edit.postDate, edit.description
+	// The time of day is kept while the date is unchanged.
+	if transaction.PostDate != nil && transaction.PostDate.Format("2006-01-02") == postDate.Format("2006-01-02") {
+		postDate = *transaction.PostDate
+	}
+	current := ""
+	if transaction.Description != nil {
+		current = *transaction.Description
+	}
+	if transaction.PostDate == nil || !transaction.PostDate.Equal(postDate) || transaction.Num != edit.num || current != description {
+		transaction.PostDate = &postDate
+		transaction.Num = edit.num
+		transaction.Description = &description
+		if err := s.Transactions.Update(ctx, transaction); err != nil {
+			return err
+		}
+	}
+
+	remaining := transaction.Splits
+	var splits []*store.Split
+	for _, edited := range edit.splits {
+		var split *store.Split
+		for i, candidate := range remaining {
+			if candidate.AccountGUID == edited.account.GUID {
+				split = candidate
+				remaining = append(remaining[:i:i], remaining[i+1:]...)
+				break
+			}
+		}
+
+		if split == nil {
+			split = &store.Split{
+				TXGUID:         transaction.GUID,
+				AccountGUID:    edited.account.GUID,
+				ReconcileState: "n",
+				Memo:           edited.memo,
+				Account:        edited.account,
+			}
+			if err := setSplitValue(split, edited.valueNum, currency, force); err != nil {
+				return err
+			}
+			if err := s.Splits.Insert(ctx, split); err != nil {
+				return err
+			}
+			splits = append(splits, split)
+			continue
+		}
+
+		if split.Memo != edited.memo || edited.valueNum != shownValueNum(split, currency) {
+			split.Memo = edited.memo
+			if edited.valueNum != shownValueNum(split, currency) {
+				if err := setSplitValue(split, edited.valueNum, currency, force); err != nil {
+					return err
+				}
+			}
+			if err := s.Splits.Update(ctx, split); err != nil {
+				return err
+			}
+		}
+		splits = append(splits, split)
+	}
+
+	for _, split := range remaining {
+		if err := s.Splits.Delete(ctx, split); err != nil {
+			return err
+		}
+	}
+
+	transaction.Splits = splits
+	return checkBalanced(transaction)
+}
+
+// runEditor opens text in $EDITOR and returns the edited text.
+func runEditor(cmd *cobra.Command, text string) (string, error) {
+	f, err := os.CreateTemp("", "gt-transaction-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+
+	c := exec.CommandContext(cmd.Context(), editor[0], append(editor[1:], f.Name())...)
+	c.Stdin = cmd.InOrStdin()
+	c.Stdout = cmd.OutOrStdout()
+	c.Stderr = cmd.ErrOrStderr()
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("editor: %w", err)
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// stripComments returns text without comment and blank lines.
+func stripComments(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			lines = append(lines, strings.TrimRight(line, " \t"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stripErrors returns text without the error comments of a previous edit.
+func stripErrors(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if !strings.HasPrefix(line, "# error: ") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffLines returns the lines removed from a prefixed with - and the lines
+// added in b prefixed with +, in order.
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var diff []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			diff = append(diff, "- "+a[i])
+			i++
+		default:
+			diff = append(diff, "+ "+b[j])
+			j++
+		}
+	}
+	return diff
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testingEditor sets $EDITOR to a script that replaces the edited file with
+// edits in turn, keeping what it was given in seen-<n> of the returned dir.
+func testingEditor(t *testing.T, edits ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for idx, edit := range edits {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("edit-%d", idx+1)), []byte(edit), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	script := filepath.Join(dir, "editor")
+	if err := os.WriteFile(script, []byte(`#!/bin/sh
+dir=$(dirname "$0")
+count=$(($(cat "$dir/count" 2>/dev/null || echo 0) + 1))
+echo $count > "$dir/count"
+cp "$1" "$dir/seen-$count"
+cp "$dir/edit-$count" "$1"
+`), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", script)
+
+	return dir
+}
+
+func TestEditTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingAccount(ctx, db, t, "HOUSEHOLDGUID", "Household", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+
+	if _, err := db.ExecContext(ctx, "UPDATE splits SET reconcile_state = 'c' WHERE guid = 'TX1-1'"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &cli{db: db}
+
+	t.Run("aborted", func(t *testing.T) {
+		dir := testingEditor(t, "")
+
+		out, err := executeCommand(editTransactionCmd(c), "TX1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "edit aborted") {
+			t.Fatalf("expected edit to be aborted but got %s", out)
+		}
+
+		given, err := os.ReadFile(filepath.Join(dir, "seen-1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range []string{"date: 2024-05-02", "description: Woolworths", "Expenses:Groceries  42.10", "Bank                -42.10"} {
+			if !strings.Contains(string(given), expected) {
+				t.Fatalf("expected %q in the edited text but got %s", expected, given)
+			}
+		}
+	})
+
+	t.Run("invalid edit reopens the editor", func(t *testing.T) {
+		dir := testingEditor(t,
+			`date: 2024-05-03
+description: Woolworths
+Expenses:Groceries  30.00
+Bank  -42.10
+`,
+			`date: 2024-05-03
+num: 7
+description: Woolworths Metro
+Expenses:Groceries  30.00  ; milk
+Expenses:Household  12.10
+Bank  -42.10
+`)
+
+		out, err := executeCommand(editTransactionCmd(c), "TX1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "+ description: Woolworths Metro") || !strings.Contains(out, "transaction updated") {
+			t.Fatalf("expected the diff and the transaction to be updated but got %s", out)
+		}
+
+		given, err := os.ReadFile(filepath.Join(dir, "seen-2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(given), "# error: transaction does not balance") {
+			t.Fatalf("expected the error at the top of the reopened text but got %s", given)
+		}
+
+		var description, postDate, num string
+		if err := db.QueryRowContext(ctx, "SELECT description, post_date, num FROM transactions WHERE guid = 'TX1'").Scan(&description, &postDate, &num); err != nil {
+			t.Fatal(err)
+		}
+		if description != "Woolworths Metro" || postDate != "2024-05-03 10:59:00" || num != "7" {
+			t.Fatalf("expected Woolworths Metro posted 2024-05-03 10:59:00 with num 7 but got %s %s %s", description, postDate, num)
+		}
+
+		rows, err := db.QueryContext(ctx, "SELECT guid, account_guid, memo, reconcile_state, value_num FROM splits WHERE tx_guid = 'TX1' ORDER BY value_num")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var got []string
+		for rows.Next() {
+			var guid, account, memo, state string
+			var value int64
+			if err := rows.Scan(&guid, &account, &memo, &state, &value); err != nil {
+				t.Fatal(err)
+			}
+			if guid != "TX1-0" && guid != "TX1-1" {
+				guid = "new"
+			}
+			got = append(got, fmt.Sprintf("%s %s %q %s %d", guid, account, memo, state, value))
+		}
+		expected := []string{
+			`TX1-1 BANKGUID "" c -4210`,
+			`new HOUSEHOLDGUID "" n 1210`,
+			`TX1-0 GROCERIESGUID "milk" n 3000`,
+		}
+		if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Fatalf("expected splits\n%s\nbut got\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+		}
+	})
+
+	t.Run("splits shown at the currency fraction", func(t *testing.T) {
+		insertTestingTransaction(ctx, db, t, "TX2", "2024-05-04", "", "GROCERIESGUID", "BANKGUID", 1000)
+		if _, err := db.ExecContext(ctx, "UPDATE transactions SET description = NULL WHERE guid = 'TX2'"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, "UPDATE splits SET value_num = value_num * 10 + 1, value_denom = 1000 WHERE guid = 'TX2-0'"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, "UPDATE splits SET value_num = value_num * 10 - 1, value_denom = 1000 WHERE guid = 'TX2-1'"); err != nil {
+			t.Fatal(err)
+		}
+
+		testingEditor(t, `date: 2024-05-04
+num: 8
+Expenses:Groceries  10.00
+Bank  -10.00
+`)
+		if _, err := executeCommand(editTransactionCmd(c), "TX2"); err != nil {
+			t.Fatal(err)
+		}
+
+		var value int64
+		var denom int64
+		if err := db.QueryRowContext(ctx, "SELECT value_num, value_denom FROM splits WHERE guid = 'TX2-0'").Scan(&value, &denom); err != nil {
+			t.Fatal(err)
+		}
+		if value != 10001 || denom != 1000 {
+			t.Fatalf("expected the unchanged split to keep 10001/1000 but got %d/%d", value, denom)
+		}
+	})
+}
@@ -0,0 +1,342 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"gt/internal/store"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var ErrExportFormat = errors.New("unsupported export format")
+
+// exportColumns are the columns of a transaction export, one row per split.
+var exportColumns = []string{"transaction_guid", "split_guid", "date", "num", "description", "account", "memo", "amount"}
+
+func exportTransactionCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		filters transactionFilterFlags
+		format  string
+	}
+	var cmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export transactions to edit them in a spreadsheet",
+		Long: `Export transactions to edit them in a spreadsheet.
+
+Every split of the transactions matching the filters is written as a
+CSV row with its transaction and split guids, so the file can be edited
+and read back with gt transaction apply. Amounts are split values in
+the transaction currency, debits positive and credits negative.`,
+		Example: `  gt transaction export --filter 'account ~ "expenses:*" and date in 2024-Q2' > transactions.csv`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flags.format != "csv" {
+				return fmt.Errorf("%w: %s", ErrExportFormat, flags.format)
+			}
+
+			s := store.NewStore(cli.db)
+			q := store.NewTransactionQuery().OrderBy("post_date", false)
+			if _, err := flags.filters.apply(cmd.Context(), &s, q); err != nil {
+				return err
+			}
+
+			transactions, err := s.Transactions.All(cmd.Context(), q)
+			if err != nil {
+				return err
+			}
+
+			currencies := make(map[string]*store.Commodity)
+			w := csv.NewWriter(cmd.OutOrStdout())
+			if err := w.Write(exportColumns); err != nil {
+				return err
+			}
+			for _, transaction := range transactions {
+				currency, err := getCurrency(cmd.Context(), &s, currencies, transaction.CurrencyGUID)
+				if err != nil {
+					return err
+				}
+
+				date, description := "", ""
+				if transaction.PostDate != nil {
+					date = transaction.PostDate.Format("2006-01-02")
+				}
+				if transaction.Description != nil {
+					description = *transaction.Description
+				}
+
+				for _, split := range transaction.Splits {
+					if err := w.Write([]string{
+						transaction.GUID,
+						split.GUID,
+						date,
+						transaction.Num,
+						description,
+						splitAccountName(split),
+						split.Memo,
+						formatAmountText(split.ValueNum, split.ValueDenom, currency),
+					}); err != nil {
+						return err
+					}
+				}
+			}
+			w.Flush()
+			return w.Error()
+		},
+	}
+	flags.filters.addFlags(cmd)
+	cmd.Flags().StringVar(&flags.format, "format", "csv", "Export format (csv)")
+	return cmd
+}
+
+func applyTransactionCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		output string
+	}
+	var cmd = &cobra.Command{
+		Use:         "apply [file]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Apply an edited transaction export",
+		Long: `Apply an edited transaction export.
+
+Each row is compared to its split and only the fields that changed are
+written: the date, num and description of the transaction and the
+account, memo and amount of the split. The rows of a transaction must
+agree on its date, num and description and its splits must balance
+after the edit. All changes are made in one database transaction.`,
+		Example: `  gt --dry-run transaction apply transactions.csv`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			rows, err := readExport(f)
+			if err != nil {
+				return err
+			}
+
+			var transactionsUpdated, splitsUpdated int
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				transactionsUpdated, splitsUpdated = 0, 0
+				currencies := make(map[string]*store.Commodity)
+
+				var order []string
+				byTransaction := make(map[string][]exportRow)
+				for _, row := range rows {
+					if _, ok := byTransaction[row.transactionGUID]; !ok {
+						order = append(order, row.transactionGUID)
+					}
+					byTransaction[row.transactionGUID] = append(byTransaction[row.transactionGUID], row)
+				}
+
+				for _, guid := range order {
+					transaction, err := txStore.Transactions.Get(cmd.Context(), guid)
+					if err != nil {
+						return fmt.Errorf("line %d: transaction %s: %w", byTransaction[guid][0].line, guid, err)
+					}
+					currency, err := getCurrency(cmd.Context(), txStore, currencies, transaction.CurrencyGUID)
+					if err != nil {
+						return err
+					}
+
+					transactionChanged, splitsChanged, err := applyExportRows(cmd.Context(), txStore, transaction, currency, byTransaction[guid], cli.force)
+					if err != nil {
+						return err
+					}
+					if transactionChanged || splitsChanged > 0 {
+						transactionsUpdated++
+						splitsUpdated += splitsChanged
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case committed:
+				fmt.Fprintf(cmd.ErrOrStderr(), "updated %d transactions and %d splits\n", transactionsUpdated, splitsUpdated)
+			case cli.dryRun:
+				fmt.Fprintf(cmd.ErrOrStderr(), "would update %d transactions and %d splits\n", transactionsUpdated, splitsUpdated)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	return cmd
+}
+
+// exportRow is a row of an edited transaction export.
+type exportRow struct {
+	line            int
+	transactionGUID string
+	splitGUID       string
+	date            string
+	num             string
+	description     string
+	account         string
+	memo            string
+	amount          string
+}
+
+// readExport reads the rows of a transaction export. Columns are found by
+// their header so they may be reordered and extra columns are ignored.
+func readExport(r io.Reader) ([]exportRow, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for idx, name := range header {
+		columns[name] = idx
+	}
+	for _, name := range exportColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %s", name)
+		}
+	}
+
+	var rows []exportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, exportRow{
+			line:            line,
+			transactionGUID: record[columns["transaction_guid"]],
+			splitGUID:       record[columns["split_guid"]],
+			date:            record[columns["date"]],
+			num:             record[columns["num"]],
+			description:     record[columns["description"]],
+			account:         record[columns["account"]],
+			memo:            record[columns["memo"]],
+			amount:          record[columns["amount"]],
+		})
+	}
+
+	return rows, nil
+}
+
+// applyExportRows applies the changes in rows to transaction and its splits
+// and checks the transaction still balances.
+func applyExportRows(ctx context.Context, s *store.Store, transaction *store.Transaction, currency *store.Commodity, rows []exportRow, force bool) (bool, int, error) {
+	first := rows[0]
+	for _, row := range rows[1:] {
+		if row.date != first.date || row.num != first.num || row.description != first.description {
+			return false, 0, fmt.Errorf("%w: line %d: date, num and description differ from line %d of the same transaction", ErrTransactionEdit, row.line, first.line)
+		}
+	}
+
+	transactionChanged := false
+	if transaction.PostDate == nil || transaction.PostDate.Format("2006-01-02") != first.date {
+		postDate, err := time.Parse("2006-01-02", first.date)
+		if err != nil {
+			return false, 0, fmt.Errorf("%w: line %d: date %q is not YYYY-MM-DD", ErrTransactionEdit, first.line, first.date)
+		}
+		postDate = store.DatePost(postDate)
+		transaction.PostDate = &postDate
+		transactionChanged = true
+	}
+	if transaction.Num != first.num {
+		transaction.Num = first.num
+		transactionChanged = true
+	}
+	description := ""
+	if transaction.Description != nil {
+		description = *transaction.Description
+	}
+	if description != first.description {
+		transaction.Description = &first.description
+		transactionChanged = true
+	}
+	if transactionChanged {
+		if err := s.Transactions.Update(ctx, transaction); err != nil {
+			return false, 0, err
+		}
+	}
+
+	splitsChanged := 0
+	for _, row := range rows {
+		var split *store.Split
+		for _, candidate := range transaction.Splits {
+			if candidate.GUID == row.splitGUID {
+				split = candidate
+				break
+			}
+		}
+		if split == nil {
+			return false, 0, fmt.Errorf("%w: line %d: split %s of transaction %s", ErrSplitNotFound, row.line, row.splitGUID, transaction.GUID)
+		}
+
+		changed := false
+		if row.account != splitAccountName(split) {
+			account, err := getAccount(ctx, s, row.account)
+			if err != nil {
+				return false, 0, fmt.Errorf("line %d: %s: %w", row.line, row.account, err)
+			}
+			if account.GUID != split.AccountGUID {
+				split.AccountGUID = account.GUID
+				split.Account = account
+				changed = true
+			}
+		}
+
+		if row.memo != split.Memo {
+			split.Memo = row.memo
+			changed = true
+		}
+
+		valueNum, err := store.ParseAmount(row.amount, currency.Fraction)
+		if err != nil {
+			return false, 0, fmt.Errorf("%w: line %d: %s", ErrTransactionEdit, row.line, err)
+		}
+		if changed || valueNum != shownValueNum(split, currency) {
+			if err := setSplitValue(split, valueNum, currency, force); err != nil {
+				return false, 0, fmt.Errorf("line %d: %w", row.line, err)
+			}
+			changed = true
+		}
+
+		if changed {
+			if err := s.Splits.Update(ctx, split); err != nil {
+				return false, 0, err
+			}
+			splitsChanged++
+		}
+	}
+
+	if err := checkBalanced(transaction); err != nil {
+		return false, 0, fmt.Errorf("transaction %s: %w", transaction.GUID, err)
+	}
+
+	return transactionChanged, splitsChanged, nil
+}
+
+// getCurrency returns the commodity identified by guid, caching it in
+// currencies.
+func getCurrency(ctx context.Context, s *store.Store, currencies map[string]*store.Commodity, guid string) (*store.Commodity, error) {
+	if currency, ok := currencies[guid]; ok {
+		return currency, nil
+	}
+	currency, err := s.Commodities.Get(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+	currencies[guid] = currency
+	return currency, nil
+}
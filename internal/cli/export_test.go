@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExportTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingAccount(ctx, db, t, "HOUSEHOLDGUID", "Household", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-03", "Bunnings, Inc", "HOUSEHOLDGUID", "BANKGUID", 1999)
+	insertTestingTransaction(ctx, db, t, "TX3", "2024-06-03", "Coles", "GROCERIESGUID", "BANKGUID", 1000)
+
+	c := &cli{db: db}
+
+	out, err := executeCommand(exportTransactionCmd(c), "--filter", "date in 2024-05")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `transaction_guid,split_guid,date,num,description,account,memo,amount
+TX1,TX1-0,2024-05-02,,Woolworths,Expenses:Groceries,,42.10
+TX1,TX1-1,2024-05-02,,Woolworths,Bank,,-42.10
+TX2,TX2-0,2024-05-03,,"Bunnings, Inc",Expenses:Household,,19.99
+TX2,TX2-1,2024-05-03,,"Bunnings, Inc",Bank,,-19.99
+`
+	if out != expected {
+		t.Fatalf("expected\n%s\nbut got\n%s", expected, out)
+	}
+
+	edited := filepath.Join(t.TempDir(), "edited.csv")
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(edited, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("unbalanced", func(t *testing.T) {
+		write(strings.Replace(expected, "Expenses:Groceries,,42.10", "Expenses:Household,,40.00", 1))
+		if _, err := executeCommand(applyTransactionCmd(c), edited); !errors.Is(err, ErrTransactionUnbalanced) {
+			t.Fatalf("expected ErrTransactionUnbalanced but got %v", err)
+		}
+
+		var account string
+		if err := db.QueryRowContext(ctx, "SELECT account_guid FROM splits WHERE guid = 'TX1-0'").Scan(&account); err != nil {
+			t.Fatal(err)
+		}
+		if account != "GROCERIESGUID" {
+			t.Fatalf("expected the edit to be rolled back but split is on %s", account)
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		write(`split_guid,transaction_guid,date,num,description,account,memo,amount,notes
+TX1-0,TX1,2024-05-02,,Woolworths,Expenses:Household,bin bags,40.00,moved
+TX1-1,TX1,2024-05-02,,Woolworths,Bank,,-40.00,
+TX2-0,TX2,2024-05-04,12,Bunnings,Expenses:Household,,19.99,
+TX2-1,TX2,2024-05-04,12,Bunnings,Bank,,-19.99,
+`)
+
+		out, err := executeCommand(applyTransactionCmd(c), edited)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "updated 2 transactions and 2 splits") {
+			t.Fatalf("expected only the changed splits to be updated but got %s", out)
+		}
+
+		var account, memo string
+		var value, quantity int64
+		if err := db.QueryRowContext(ctx, "SELECT account_guid, memo, value_num, quantity_num FROM splits WHERE guid = 'TX1-0'").Scan(&account, &memo, &value, &quantity); err != nil {
+			t.Fatal(err)
+		}
+		if account != "HOUSEHOLDGUID" || memo != "bin bags" || value != 4000 || quantity != 4000 {
+			t.Fatalf("expected split on HOUSEHOLDGUID with memo bin bags of 4000 but got %s %s %d %d", account, memo, value, quantity)
+		}
+
+		var postDate, num string
+		if err := db.QueryRowContext(ctx, "SELECT post_date, num FROM transactions WHERE guid = 'TX2'").Scan(&postDate, &num); err != nil {
+			t.Fatal(err)
+		}
+		if postDate != "2024-05-04 10:59:00" || num != "12" {
+			t.Fatalf("expected TX2 posted 2024-05-04 10:59:00 with num 12 but got %s %s", postDate, num)
+		}
+		assertTransactionDescription(ctx, db, t, "TX2", "Bunnings")
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		// Neither a missing description nor values at a finer denominator
+		// than the currency are changes once exported.
+		if _, err := db.ExecContext(ctx, "UPDATE transactions SET description = NULL WHERE guid = 'TX3'"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, "UPDATE splits SET value_num = value_num * 10, value_denom = 1000 WHERE tx_guid = 'TX3'"); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := executeCommand(exportTransactionCmd(c), "--filter", "date in 2024-06")
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(out)
+
+		out, err = executeCommand(applyTransactionCmd(c), edited)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "updated 0 transactions and 0 splits") {
+			t.Fatalf("expected nothing updated but got %s", out)
+		}
+	})
+
+	t.Run("rows disagree", func(t *testing.T) {
+		write(`transaction_guid,split_guid,date,num,description,account,memo,amount
+TX3,TX3-0,2024-06-03,,Coles,Expenses:Groceries,,10.00
+TX3,TX3-1,2024-06-04,,Coles,Bank,,-10.00
+`)
+		if _, err := executeCommand(applyTransactionCmd(c), edited); !errors.Is(err, ErrTransactionEdit) {
+			t.Fatalf("expected ErrTransactionEdit but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"gt/internal/journal"
+	"gt/internal/render"
+	"gt/internal/store"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ErrJournalMissing = errors.New("no journal, gnucash_db_file is not set")
+	ErrNothingToUndo  = errors.New("nothing to undo")
+	ErrAlreadyUndone  = errors.New("operation already undone")
+	ErrOperationIDBad = errors.New("operation id must be a number")
+	ErrUndoRestored   = errors.New("can not undo an operation performed before a restore")
+)
+
+func historyCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		limit  int
+		output string
+	}
+	var cmd = &cobra.Command{
+		Use:   "history",
+		Short: "List the operations gt performed on the book",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			j := cli.journal()
+			if j == nil {
+				return ErrJournalMissing
+			}
+
+			entries, err := j.All()
+			if err != nil {
+				return err
+			}
+
+			if flags.limit > 0 && len(entries) > flags.limit {
+				entries = entries[len(entries)-flags.limit:]
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			return r.Render(cmd.OutOrStdout(), entries)
+		},
+	}
+	cmd.Flags().IntVar(&flags.limit, "limit", 50, "Limit")
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	return cmd
+}
+
+func undoCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		output string
+	}
+	var cmd = &cobra.Command{
+		Use:         "undo [operation-id]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Undo an operation",
+		Args:        cobra.MaximumNArgs(1),
+		Long: `Undo an operation listed by gt history by writing back the rows as
+they were before the operation. Without an operation id the most recent
+operation that has not been undone is undone.
+
+Undo refuses to run when any of the rows the operation changed have
+been changed since, for example by gnucash, and for operations
+performed before the book was restored from a backup.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			j := cli.journal()
+			if j == nil {
+				return ErrJournalMissing
+			}
+
+			entries, err := j.All()
+			if err != nil {
+				return err
+			}
+
+			var entry *journal.Entry
+			if len(args) == 1 {
+				id, err := strconv.Atoi(args[0])
+				if err != nil {
+					return ErrOperationIDBad
+				}
+				if entry, err = j.Get(id); err != nil {
+					return err
+				}
+				if entry.UndoneBy != 0 {
+					return fmt.Errorf("%w by operation %d", ErrAlreadyUndone, entry.UndoneBy)
+				}
+				for _, e := range entries {
+					if e.ID > entry.ID && e.Restore != "" {
+						return fmt.Errorf("%w: operation %d, restored from %s by operation %d", ErrUndoRestored, entry.ID, e.Restore, e.ID)
+					}
+				}
+			} else {
+				for i := len(entries) - 1; i >= 0; i-- {
+					if entries[i].Restore != "" {
+						break
+					}
+					if entries[i].UndoneBy == 0 && entries[i].Undoes == 0 {
+						entry = entries[i]
+						break
+					}
+				}
+				if entry == nil {
+					return ErrNothingToUndo
+				}
+			}
+
+			undo := &journal.Entry{Undoes: entry.ID}
+			committed, err := cli.writeEntry(cmd, flags.output, undo, func(txStore *store.Store) error {
+				for i := len(entry.Changes) - 1; i >= 0; i-- {
+					if err := txStore.Revert(cmd.Context(), entry.Changes[i]); err != nil {
+						return fmt.Errorf("can not undo operation %d: %w", entry.ID, err)
+					}
+				}
+				return nil
+			})
+			if err != nil || !committed {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			return r.Render(cmd.OutOrStdout(), undo.Changes)
+		},
+	}
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	return cmd
+}
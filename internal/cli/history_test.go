@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"gt/internal/journal"
+	"gt/internal/store"
+	"os"
+	"testing"
+)
+
+func TestUndoCmd(t *testing.T) {
+	ctx := context.Background()
+
+	f, _ := os.CreateTemp("", "testdb-*.sqlite")
+	dsn := f.Name()
+	f.Close()
+	defer os.Remove(dsn)
+	defer os.Remove(journal.Path(dsn))
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err = createTestingTables(ctx, db, t); err != nil {
+		t.Fatal(err)
+	}
+	insertTestingAccount(ctx, db, t, "2", "test1", "EXPENSE", "EXPENSESGUID")
+
+	c := &cli{db: db, config: config{GnucashDBFile: dsn}}
+	if _, err := executeCommand(updateAccountCmd(c), "expenses:test1", "--name=test2", "--output=json"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := executeCommand(historyCmd(c), "--output=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entries []*journal.Entry
+	if err := json.Unmarshal([]byte(out), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || len(entries[0].Changes) != 1 {
+		t.Fatalf("expected one journal entry with one change but got %v", entries)
+	}
+
+	if _, err := executeCommand(undoCmd(c), "--output=json"); err != nil {
+		t.Fatal(err)
+	}
+
+	var name string
+	if err := db.QueryRowContext(ctx, "SELECT name FROM accounts WHERE guid=?", "2").Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	if name != "test1" {
+		t.Fatalf("expected undo to restore name test1 but got %s", name)
+	}
+
+	if _, err := executeCommand(undoCmd(c)); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("expected ErrNothingToUndo but got %v", err)
+	}
+
+	// A row changed outside of gt must not be overwritten by undo.
+	if _, err := executeCommand(updateAccountCmd(c), "expenses:test1", "--name=test3", "--output=json"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "UPDATE accounts SET description=? WHERE guid=?", "changed by gnucash", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := executeCommand(undoCmd(c)); !errors.Is(err, store.ErrRowChanged) {
+		t.Fatalf("expected ErrRowChanged but got %v", err)
+	}
+}
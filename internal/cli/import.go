@@ -0,0 +1,430 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gt/internal/match"
+	"gt/internal/render"
+	"gt/internal/statement"
+	"gt/internal/store"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// onlineIDSlot is the split slot gnucash's own importers use to remember the
+// bank's reference for an imported split.
+const onlineIDSlot = "online_id"
+
+var ErrStatementFormat = errors.New("unsupported statement format")
+
+// statementParsers are the statement formats gt reads keyed by name.
+var statementParsers = map[string]func(io.Reader) ([]statement.Line, error){
+	"camt053": statement.ParseCAMT053,
+	"mt940":   statement.ParseMT940,
+}
+
+type importFlags struct {
+	account          string
+	balancingAccount string
+	output           string
+	noMatch          bool
+	window           int
+	minSimilarity    float64
+}
+
+// importReport is the outcome of importing or matching statement lines.
+type importReport struct {
+	transactions []*store.Transaction
+	// results holds the lines matched to existing transactions, the lines
+	// that need to be resolved by hand and, when only matching, the lines
+	// without a match.
+	results []match.Result
+	skipped int
+}
+
+func importCmd(cli *cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "import",
+		Short: "Import bank statements",
+	}
+	cmd.AddCommand(importStatementCmd(cli, "camt053", "ISO 20022 camt.053", statement.ParseCAMT053))
+	cmd.AddCommand(importStatementCmd(cli, "mt940", "SWIFT MT940", statement.ParseMT940))
+	cmd.AddCommand(matchStatementCmd(cli))
+	return cmd
+}
+
+func importStatementCmd(cli *cli, use, format string, parse func(io.Reader) ([]statement.Line, error)) *cobra.Command {
+	var flags importFlags
+	var cmd = &cobra.Command{
+		Use:         use + " [file]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Import a " + format + " statement",
+		Args:        cobra.ExactArgs(1),
+		Long: `Import a ` + format + ` bank statement into an account.
+
+Each statement line becomes a transaction with one split on the
+statement account and one on the balancing account, which defaults to
+Imbalance-<currency>. The bank reference of each line is stored in the
+split's online_id slot and lines that have already been imported are
+skipped.
+
+Lines that match a transaction already entered by hand are not
+imported, the matched split is marked cleared instead. Lines with more
+than one possible match are not imported either and are reported for
+manual resolution. See gt import match for how lines are matched. Use
+--no-match to import every line.`,
+		Example: `  gt import ` + use + ` statement.` + use + ` --account assets:bank`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			lines, err := parse(f)
+			if err != nil {
+				return err
+			}
+
+			var report *importReport
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				var err error
+				report, err = importLines(cmd.Context(), txStore, flags, lines, true)
+				return err
+			})
+			if err != nil || !committed {
+				return err
+			}
+
+			if report.skipped > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "skipped %d already imported statement lines\n", report.skipped)
+			}
+			for _, result := range report.results {
+				fmt.Fprintln(cmd.ErrOrStderr(), describeMatch(result))
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			return r.Render(cmd.OutOrStdout(), report.transactions)
+		},
+	}
+	cmd.Flags().StringVar(&flags.account, "account", "", "Statement Account GUID or Full Account Name")
+	cmd.Flags().StringVar(&flags.balancingAccount, "balancing-account", "", "Balancing Account GUID or Full Account Name")
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.Flags().BoolVar(&flags.noMatch, "no-match", false, "Import every line without matching it to existing transactions")
+	cmd.Flags().IntVar(&flags.window, "window", 3, FlagsUsageMatchWindow)
+	cmd.Flags().Float64Var(&flags.minSimilarity, "min-similarity", 0.5, FlagsUsageMatchMinSimilarity)
+	cmd.MarkFlagRequired("account")
+	return cmd
+}
+
+func matchStatementCmd(cli *cli) *cobra.Command {
+	var flags importFlags
+	var format string
+	var cmd = &cobra.Command{
+		Use:         "match [file]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Match a statement to existing transactions",
+		Args:        cobra.ExactArgs(1),
+		Long: `Match the lines of a bank statement to the transactions of an account
+without importing anything.
+
+Candidates for a line are the unreconciled splits of the account with
+the same amount posted within --window days of the line. A candidate
+is confident when it is posted on the line's booking date or its
+description shares at least --min-similarity of its words with the
+line. A line matches its only confident candidate, or the confident
+candidate with the most similar description. Matched splits are marked
+cleared and remember the line's bank reference so the line is skipped
+by later imports. Lines with more than one possible match and lines
+without a match are reported.`,
+		Example: `  gt import match statement.xml --account assets:bank --window 5`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			if format == "" {
+				format = detectStatementFormat(b)
+			}
+			parse, ok := statementParsers[format]
+			if !ok {
+				return fmt.Errorf("%w: %s", ErrStatementFormat, format)
+			}
+
+			lines, err := parse(bytes.NewReader(b))
+			if err != nil {
+				return err
+			}
+
+			var report *importReport
+			_, err = cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				var err error
+				report, err = importLines(cmd.Context(), txStore, flags, lines, false)
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if report.skipped > 0 {
+				fmt.Fprintf(cmd.ErrOrStderr(), "skipped %d already imported statement lines\n", report.skipped)
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			return r.Render(cmd.OutOrStdout(), report.results)
+		},
+	}
+	cmd.Flags().StringVar(&flags.account, "account", "", "Statement Account GUID or Full Account Name")
+	cmd.Flags().StringVar(&format, "format", "", "Statement format (camt053, mt940), detected from the file when not set")
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.Flags().IntVar(&flags.window, "window", 3, FlagsUsageMatchWindow)
+	cmd.Flags().Float64Var(&flags.minSimilarity, "min-similarity", 0.5, FlagsUsageMatchMinSimilarity)
+	cmd.MarkFlagRequired("account")
+	return cmd
+}
+
+// detectStatementFormat tells camt.053 XML from MT940 text.
+func detectStatementFormat(b []byte) string {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("<")) {
+		return "camt053"
+	}
+	return "mt940"
+}
+
+// describeMatch describes a matched or ambiguous statement line.
+func describeMatch(result match.Result) string {
+	line := fmt.Sprintf("statement line %s %s %q", result.Line.BookingDate.Format("2006-01-02"), result.Line.Amount, result.Line.Description())
+	switch result.Status {
+	case match.StatusMatched:
+		return fmt.Sprintf("%s matched transaction %s", line, result.Match.Transaction.GUID)
+	case match.StatusAmbiguous:
+		guids := make([]string, len(result.Candidates))
+		for i, candidate := range result.Candidates {
+			guids[i] = candidate.Transaction.GUID
+		}
+		return fmt.Sprintf("%s is ambiguous and was not imported, candidates %s", line, strings.Join(guids, ", "))
+	default:
+		return fmt.Sprintf("%s has no match", line)
+	}
+}
+
+// importLines imports lines into the statement account. Lines matched to
+// existing transactions mark them cleared instead and, when create is false,
+// unmatched lines are only reported rather than imported.
+func importLines(ctx context.Context, txStore *store.Store, flags importFlags, lines []statement.Line, create bool) (*importReport, error) {
+	account, err := getAccount(ctx, txStore, flags.account)
+	if err != nil {
+		return nil, err
+	}
+	if account.CommodityGUID == nil {
+		return nil, fmt.Errorf("account %s has no commodity", account.FullName)
+	}
+
+	commodity, err := txStore.Commodities.Get(ctx, *account.CommodityGUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balancingAccount *store.Account
+	if create {
+		if flags.balancingAccount != "" {
+			balancingAccount, err = getAccount(ctx, txStore, flags.balancingAccount)
+		} else {
+			balancingAccount, err = getImbalanceAccount(ctx, txStore, commodity)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if balancingAccount.CommodityGUID == nil || *balancingAccount.CommodityGUID != commodity.GUID {
+			return nil, fmt.Errorf("balancing account %s must be in %s", balancingAccount.FullName, commodity.Mnemonic)
+		}
+	}
+
+	var matcher *match.Matcher
+	if !flags.noMatch {
+		matcher, err = newStatementMatcher(ctx, txStore, account, lines, flags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	enterDate := time.Now().UTC().Truncate(time.Second)
+	seen := make(map[string]bool)
+	report := &importReport{transactions: []*store.Transaction{}, results: []match.Result{}}
+	for _, line := range lines {
+		if line.Currency != "" && !strings.EqualFold(line.Currency, commodity.Mnemonic) {
+			return nil, fmt.Errorf("statement line %s %s %s does not match account currency %s", line.BookingDate.Format("2006-01-02"), line.Amount, line.Currency, commodity.Mnemonic)
+		}
+
+		if line.Reference != "" {
+			if seen[line.Reference] {
+				report.skipped++
+				continue
+			}
+			seen[line.Reference] = true
+
+			q := store.NewSlotQuery().
+				Where("name=?", onlineIDSlot).
+				Where("string_val=?", line.Reference).
+				Where("obj_guid IN (SELECT guid FROM splits WHERE account_guid=?)", account.GUID).
+				Limit(1)
+			existing, err := txStore.Slots.All(ctx, q)
+			if err != nil {
+				return nil, err
+			}
+			if len(existing) > 0 {
+				report.skipped++
+				continue
+			}
+		}
+
+		quantity, err := line.Value(account.CommoditySCU)
+		if err != nil {
+			return nil, err
+		}
+
+		if matcher != nil {
+			result := matcher.Match(line, quantity, account.CommoditySCU)
+			switch {
+			case result.Status == match.StatusMatched:
+				if err := clearMatchedSplit(ctx, txStore, result); err != nil {
+					return nil, err
+				}
+				report.results = append(report.results, result)
+				continue
+			case result.Status == match.StatusAmbiguous || !create:
+				report.results = append(report.results, result)
+				continue
+			}
+		}
+
+		value, err := line.Value(commodity.Fraction)
+		if err != nil {
+			return nil, err
+		}
+
+		description := line.Description()
+		postDate := store.DatePost(line.BookingDate)
+		transaction := &store.Transaction{
+			CurrencyGUID: commodity.GUID,
+			PostDate:     &postDate,
+			EnterDate:    &enterDate,
+			Description:  &description,
+			Splits: []*store.Split{
+				{
+					AccountGUID:    account.GUID,
+					Memo:           line.Remittance,
+					ReconcileState: "n",
+					ValueNum:       value,
+					ValueDenom:     commodity.Fraction,
+					QuantityNum:    quantity,
+					QuantityDenom:  account.CommoditySCU,
+					Account:        account,
+				},
+				{
+					AccountGUID:    balancingAccount.GUID,
+					ReconcileState: "n",
+					ValueNum:       -value,
+					ValueDenom:     commodity.Fraction,
+					QuantityNum:    store.ConvertNum(-value, commodity.Fraction, balancingAccount.CommoditySCU),
+					QuantityDenom:  balancingAccount.CommoditySCU,
+					Account:        balancingAccount,
+				},
+			},
+		}
+
+		if err := txStore.Transactions.Insert(ctx, transaction); err != nil {
+			return nil, err
+		}
+
+		if err := setOnlineID(ctx, txStore, transaction.Splits[0].GUID, line.Reference); err != nil {
+			return nil, err
+		}
+
+		report.transactions = append(report.transactions, transaction)
+	}
+
+	return report, nil
+}
+
+// newStatementMatcher returns a matcher for the splits of account posted
+// around the booking dates of lines. Splits imported from another statement
+// line are not matched.
+func newStatementMatcher(ctx context.Context, s *store.Store, account *store.Account, lines []statement.Line, flags importFlags) (*match.Matcher, error) {
+	opts := []match.MatcherOptFunc{
+		match.WithWindow(flags.window),
+		match.WithMinSimilarity(flags.minSimilarity),
+	}
+	if len(lines) == 0 {
+		return match.NewMatcher(account.GUID, nil, opts...), nil
+	}
+
+	from, to := lines[0].BookingDate, lines[0].BookingDate
+	for _, line := range lines {
+		if line.BookingDate.Before(from) {
+			from = line.BookingDate
+		}
+		if line.BookingDate.After(to) {
+			to = line.BookingDate
+		}
+	}
+
+	q := store.NewTransactionQuery().
+		WhereAccount(account.GUID, false).
+		Where("transactions.post_date >= ?", from.AddDate(0, 0, -flags.window-1).Format("2006-01-02")).
+		Where("transactions.post_date < ?", to.AddDate(0, 0, flags.window+2).Format("2006-01-02"))
+	transactions, err := s.Transactions.All(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := match.NewMatcher(account.GUID, transactions, opts...)
+
+	imported, err := s.Slots.All(ctx, store.NewSlotQuery().
+		Where("name=?", onlineIDSlot).
+		Where("obj_guid IN (SELECT guid FROM splits WHERE account_guid=?)", account.GUID))
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range imported {
+		matcher.Exclude(slot.ObjGUID)
+	}
+
+	return matcher, nil
+}
+
+// clearMatchedSplit marks the split a statement line matched as cleared and
+// stores the line's bank reference on it so the line is not imported later.
+func clearMatchedSplit(ctx context.Context, s *store.Store, result match.Result) error {
+	split := result.Match.Split
+	if split.ReconcileState == "n" {
+		split.ReconcileState = "c"
+		if err := s.Splits.Update(ctx, split); err != nil {
+			return err
+		}
+	}
+	return setOnlineID(ctx, s, split.GUID, result.Line.Reference)
+}
+
+// setOnlineID stores the bank reference of a statement line on split.
+func setOnlineID(ctx context.Context, s *store.Store, splitGUID, reference string) error {
+	if reference == "" {
+		return nil
+	}
+	return s.Slots.Set(ctx, store.NewStringSlot(splitGUID, onlineIDSlot, reference))
+}
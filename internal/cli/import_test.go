@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"gt/internal/match"
+	"gt/internal/store"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testMT940 = `:20:STARTUMS
+:25:12345678/0001234567
+:28C:00001/001
+:60F:C240501AUD1000,00
+:61:2405020502D42,10NTRFNONREF//BANKREF1
+:86:166?00SEPA-UEBERWEISUNG?20Weekly shop
+?32Woolworths
+:61:2405030503C1500,NTRFNONREF//BANKREF2
+:86:/NAME/ACME PTY LTD/REMI/Salary
+May
+:62F:C240503AUD2457,90
+-`
+
+const testCAMT053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Ntry>
+        <Amt Ccy="AUD">42.10</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><Dt>2024-05-02</Dt></BookgDt>
+        <AcctSvcrRef>CAMTREF1</AcctSvcrRef>
+        <NtryDtls>
+          <TxDtls>
+            <RltdPties><Cdtr><Nm>Woolworths</Nm></Cdtr></RltdPties>
+            <RmtInf><Ustrd>Weekly shop</Ustrd></RmtInf>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+func TestImportCmd(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		format   string
+		contents string
+		expected []struct {
+			description string
+			memo        string
+			valueNum    int64
+		}
+	}{
+		{
+			name:     "mt940",
+			format:   "mt940",
+			contents: testMT940,
+			expected: []struct {
+				description string
+				memo        string
+				valueNum    int64
+			}{
+				{description: "Woolworths", memo: "Weekly shop", valueNum: -4210},
+				{description: "ACME PTY LTD", memo: "Salary May", valueNum: 150000},
+			},
+		},
+		{
+			name:     "camt053",
+			format:   "camt053",
+			contents: testCAMT053,
+			expected: []struct {
+				description string
+				memo        string
+				valueNum    int64
+			}{
+				{description: "Woolworths", memo: "Weekly shop", valueNum: -4210},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			f, _ := os.CreateTemp("", "testdb-*.sqlite")
+			dsn := f.Name()
+			f.Close()
+			defer os.Remove(dsn)
+
+			db, err := sql.Open("sqlite3", dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			if err = createTestingTables(ctx, db, t); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = db.ExecContext(ctx,
+				"INSERT INTO accounts (guid, name, account_type, commodity_guid, parent_guid, commodity_scu, non_std_scu) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				"BANKGUID",
+				"Bank",
+				"BANK",
+				"AUDGUID",
+				"ROOTGUID",
+				100,
+				0); err != nil {
+				t.Fatal(err)
+			}
+
+			statementFile, err := os.CreateTemp("", "statement-*")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(statementFile.Name())
+			if _, err := statementFile.WriteString(tc.contents); err != nil {
+				t.Fatal(err)
+			}
+			statementFile.Close()
+
+			c := &cli{db: db}
+			out, err := executeCommand(importCmd(c), tc.format, statementFile.Name(), "--account", "bank", "--output", "json")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resp []store.Transaction
+			if err := json.Unmarshal([]byte(out), &resp); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(resp) != len(tc.expected) {
+				t.Fatalf("expected %d transactions but got %d", len(tc.expected), len(resp))
+			}
+
+			for i, expected := range tc.expected {
+				transaction := resp[i]
+				if *transaction.Description != expected.description {
+					t.Fatalf("expected description %s but got %s", expected.description, *transaction.Description)
+				}
+				if transaction.Splits[0].Memo != expected.memo {
+					t.Fatalf("expected memo %s but got %s", expected.memo, transaction.Splits[0].Memo)
+				}
+				if transaction.Splits[0].ValueNum != expected.valueNum {
+					t.Fatalf("expected value %d but got %d", expected.valueNum, transaction.Splits[0].ValueNum)
+				}
+				if transaction.Splits[1].Account.Name != "Imbalance-AUD" {
+					t.Fatalf("expected balancing account Imbalance-AUD but got %s", transaction.Splits[1].Account.Name)
+				}
+			}
+
+			// Importing the same statement again must not create duplicates.
+			if _, err := executeCommand(importCmd(c), tc.format, statementFile.Name(), "--account", "bank", "--output", "json"); err != nil {
+				t.Fatal(err)
+			}
+
+			var count int
+			if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions").Scan(&count); err != nil {
+				t.Fatal(err)
+			}
+			if count != len(tc.expected) {
+				t.Fatalf("expected %d transactions after re-import but got %d", len(tc.expected), count)
+			}
+		})
+	}
+}
+
+func TestMatchStatementCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingAccount(ctx, db, t, "SAVINGSGUID", "Savings", "BANK", "ROOTGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-01", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-02", "Transfer", "BANKGUID", "SAVINGSGUID", 150000)
+	insertTestingTransaction(ctx, db, t, "TX3", "2024-05-04", "Transfer", "BANKGUID", "SAVINGSGUID", 150000)
+
+	statementFile, err := os.CreateTemp("", "statement-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(statementFile.Name())
+	if _, err := statementFile.WriteString(testMT940); err != nil {
+		t.Fatal(err)
+	}
+	statementFile.Close()
+
+	c := &cli{db: db}
+	out, err := executeCommand(importCmd(c), "match", statementFile.Name(), "--account", "bank", "--output", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var results []match.Result
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results but got %d", len(results))
+	}
+	if results[0].Status != match.StatusMatched || results[0].Match.Transaction.GUID != "TX1" {
+		t.Fatalf("expected first line to match TX1 but got %+v", results[0])
+	}
+	if results[1].Status != match.StatusAmbiguous || len(results[1].Candidates) != 2 {
+		t.Fatalf("expected second line to be ambiguous but got %+v", results[1])
+	}
+
+	var state string
+	if err := db.QueryRowContext(ctx, "SELECT reconcile_state FROM splits WHERE guid='TX1-1'").Scan(&state); err != nil {
+		t.Fatal(err)
+	}
+	if state != "c" {
+		t.Fatalf("expected matched split to be cleared but got %s", state)
+	}
+
+	// The matched line is skipped and the ambiguous line is not imported.
+	out, err = executeCommand(importCmd(c), "mt940", statementFile.Name(), "--account", "bank", "--output", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "skipped 1 already imported statement lines") || !strings.Contains(out, "is ambiguous") {
+		t.Fatalf("expected skipped and ambiguous lines to be reported but got %s", out)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Fatalf("expected no transactions to be imported but got %d transactions", count)
+	}
+}
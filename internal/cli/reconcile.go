@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"gt/internal/reconcile"
+	"gt/internal/render"
+	"gt/internal/store"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Gnucash remembers the last statement date an account was reconciled to in
+// the reconcile-info/last-date slot, an int64 of seconds since the epoch
+// inside the reconcile-info frame.
+const (
+	reconcileInfoSlot     = "reconcile-info"
+	reconcileLastDateSlot = "reconcile-info/last-date"
+)
+
+func reconcileCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		statementDate string
+		endingBalance string
+		splits        []string
+		filter        string
+		interactive   bool
+		output        string
+	}
+	var cmd = &cobra.Command{
+		Use:   "reconcile [account]",
+		Short: "Reconcile an account against a bank statement",
+		Args:  cobra.ExactArgs(1),
+		Long: `Reconcile an account against a bank statement.
+
+The unreconciled splits of the account posted up to the statement date
+are listed with cleared splits selected. Select the splits on the
+statement with --split or interactively with --interactive. Once the
+selected splits bring the reconciled balance to the ending balance
+they are marked reconciled as of the statement date in one database
+transaction and the statement date is recorded as the account's last
+reconcile date. Otherwise the remaining difference is shown and
+nothing is changed. With --filter only the unreconciled splits of
+matching transactions are listed, reconciled splits still count
+towards the starting balance.`,
+		Example: `  gt reconcile assets:bank --statement-date 2024-05-31 --ending-balance 1234.56 --interactive
+  gt reconcile assets:bank --statement-date 2024-05-31 --ending-balance 1234.56 \
+    --split 0000000000000000fa1ce5381fec0d51 --split 0000000000000000fa1ce5381fec0d52`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			statementDate, err := time.ParseInLocation("2006-01-02", flags.statementDate, time.Local)
+			if err != nil {
+				return err
+			}
+
+			s := store.NewStore(cli.db)
+			account, err := getAccount(cmd.Context(), &s, args[0])
+			if err != nil {
+				return err
+			}
+
+			denom := account.CommoditySCU
+			if denom == 0 {
+				denom = 100
+			}
+
+			endingBalance, err := store.ParseAmount(flags.endingBalance, denom)
+			if err != nil {
+				return err
+			}
+
+			// Like gnucash, the statement covers the whole statement date.
+			statementEnd := statementDate.AddDate(0, 0, 1)
+			load := func(s *store.Store) (*reconcile.Reconciliation, error) {
+				entries, err := s.Register(cmd.Context(), account, nil, &statementEnd)
+				if err != nil {
+					return nil, err
+				}
+				entries, err = filterRegister(cmd.Context(), s, account, entries, flags.filter, func(entry *store.RegisterEntry) bool {
+					return entry.ReconcileState == "y"
+				})
+				if err != nil {
+					return nil, err
+				}
+				return reconcile.New(account.FullName, statementDate, endingBalance, denom, entries), nil
+			}
+
+			r, err := load(&s)
+			if err != nil {
+				return err
+			}
+			for _, guid := range flags.splits {
+				if err := r.Select(guid); err != nil {
+					return err
+				}
+			}
+
+			if flags.interactive {
+				if err := reconcileInteractively(cmd, r); err != nil {
+					return err
+				}
+			}
+
+			rr, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			if !r.Balanced() {
+				fmt.Fprintf(cmd.ErrOrStderr(), "difference of %s remaining, nothing reconciled\n", new(big.Rat).SetFrac64(r.Difference, r.Denom).FloatString(2))
+				return rr.Render(cmd.OutOrStdout(), r)
+			}
+
+			// Only marking the splits reconciled writes to the book so the
+			// command is not annotated as writing and read-only mode is
+			// checked here.
+			if cli.readOnly {
+				return fmt.Errorf("%w, %s writes to the book once the splits balance", ErrReadOnly, cmd.CommandPath())
+			}
+
+			var selected []string
+			for _, item := range r.Selected() {
+				selected = append(selected, item.SplitGUID)
+			}
+
+			// The register is read again in the write transaction so the
+			// splits marked reconciled still balance once the book is locked.
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				current, err := load(txStore)
+				if err != nil {
+					return err
+				}
+				if err := current.SelectOnly(selected); err != nil {
+					return fmt.Errorf("book changed while reconciling: %w", err)
+				}
+				if !current.Balanced() {
+					return fmt.Errorf("book changed while reconciling: %w", reconcile.ErrNotBalanced)
+				}
+				r = current
+
+				reconcileDate := statementEnd.Add(-time.Second).UTC()
+				for _, item := range r.Selected() {
+					splits, err := txStore.Splits.All(cmd.Context(), store.NewSplitQuery().Where("guid=?", item.SplitGUID))
+					if err != nil {
+						return err
+					}
+					for _, split := range splits {
+						split.ReconcileState = "y"
+						split.ReconcileDate = &reconcileDate
+						if err := txStore.Splits.Update(cmd.Context(), split); err != nil {
+							return err
+						}
+					}
+				}
+
+				return setReconcileLastDate(cmd.Context(), txStore, account.GUID, reconcileDate)
+			})
+			if err != nil || !committed {
+				return err
+			}
+			r.Reconciled = true
+
+			return rr.Render(cmd.OutOrStdout(), r)
+		},
+	}
+	cmd.Flags().StringVar(&flags.statementDate, "statement-date", "", "Statement date")
+	cmd.Flags().StringVar(&flags.endingBalance, "ending-balance", "", "Statement ending balance")
+	cmd.Flags().StringArrayVar(&flags.splits, "split", nil, "GUID of a split on the statement (repeatable)")
+	cmd.Flags().StringVar(&flags.filter, "filter", "", FlagsUsageFilter)
+	cmd.Flags().BoolVar(&flags.interactive, "interactive", false, "Select the splits on the statement interactively")
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.MarkFlagRequired("statement-date")
+	cmd.MarkFlagRequired("ending-balance")
+	return cmd
+}
+
+// reconcileInteractively shows the reconciliation and toggles the splits the
+// user enters by number until an empty line.
+func reconcileInteractively(cmd *cobra.Command, r *reconcile.Reconciliation) error {
+	table, err := render.New(string(render.FormatTable))
+	if err != nil {
+		return err
+	}
+
+	for {
+		if err := table.Render(cmd.ErrOrStderr(), r); err != nil {
+			return err
+		}
+		fmt.Fprint(cmd.ErrOrStderr(), "Toggle splits by number, empty line when done: ")
+
+		line, err := readLine(cmd.InOrStdin())
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return nil
+		}
+
+		for _, field := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' }) {
+			number, convErr := strconv.Atoi(field)
+			if convErr != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%q is not a split number\n", field)
+				continue
+			}
+			if toggleErr := r.Toggle(number); toggleErr != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), toggleErr)
+			}
+		}
+
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+// setReconcileLastDate records date as the last date the account was
+// reconciled to, creating the reconcile-info frame if needed.
+func setReconcileLastDate(ctx context.Context, s *store.Store, accountGUID string, date time.Time) error {
+	frame, err := s.Slots.Get(ctx, accountGUID, reconcileInfoSlot)
+	if err != nil {
+		return err
+	}
+
+	var frameGUID string
+	if frame != nil && frame.SlotType == store.SlotTypeFrame && frame.GUIDVal != nil {
+		frameGUID = *frame.GUIDVal
+	} else {
+		frameGUID = store.NewGUID()
+		frame := &store.Slot{
+			ObjGUID:  accountGUID,
+			Name:     reconcileInfoSlot,
+			SlotType: store.SlotTypeFrame,
+			GUIDVal:  &frameGUID,
+		}
+		if err := s.Slots.Insert(ctx, frame); err != nil {
+			return err
+		}
+	}
+
+	lastDate := date.Unix()
+	return s.Slots.Set(ctx, &store.Slot{
+		ObjGUID:  frameGUID,
+		Name:     reconcileLastDateSlot,
+		SlotType: store.SlotTypeInt64,
+		Int64Val: &lastDate,
+	})
+}
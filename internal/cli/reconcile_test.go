@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gt/internal/reconcile"
+	"strings"
+	"testing"
+)
+
+func TestReconcileCmd(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*cli, func()) {
+		db, cleanup := newTestingDB(ctx, t)
+		insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+		insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+		insertTestingTransaction(ctx, db, t, "TX1", "2024-05-01", "Opening", "BANKGUID", "ROOTGUID", 100000)
+		insertTestingTransaction(ctx, db, t, "TX2", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+		insertTestingTransaction(ctx, db, t, "TX3", "2024-06-02", "Coles", "GROCERIESGUID", "BANKGUID", 6000)
+		if _, err := db.ExecContext(ctx, "UPDATE splits SET reconcile_state='c' WHERE guid='TX2-1'"); err != nil {
+			t.Fatal(err)
+		}
+		return &cli{db: db}, cleanup
+	}
+
+	reconcileStates := func(t *testing.T, c *cli) map[string]string {
+		rows, err := c.db.QueryContext(ctx, "SELECT guid, reconcile_state FROM splits WHERE account_guid='BANKGUID'")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		states := make(map[string]string)
+		for rows.Next() {
+			var guid, state string
+			if err := rows.Scan(&guid, &state); err != nil {
+				t.Fatal(err)
+			}
+			states[guid] = state
+		}
+		return states
+	}
+
+	t.Run("difference remaining", func(t *testing.T) {
+		c, cleanup := setup(t)
+		defer cleanup()
+
+		out, err := executeCommand(reconcileCmd(c), "bank", "--statement-date=2024-05-31", "--ending-balance=957.90")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "difference of 1000.00 remaining") {
+			t.Fatalf("expected remaining difference but got %s", out)
+		}
+		if states := reconcileStates(t, c); states["TX2-1"] != "c" {
+			t.Fatalf("expected nothing reconciled but got %v", states)
+		}
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		c, cleanup := setup(t)
+		defer cleanup()
+		c.readOnly = true
+
+		out, err := executeCommand(reconcileCmd(c), "bank", "--statement-date=2024-05-31", "--ending-balance=957.90")
+		if err != nil {
+			t.Fatalf("expected splits listed in read-only mode but got %v", err)
+		}
+		if !strings.Contains(out, "difference of 1000.00 remaining") {
+			t.Fatalf("expected remaining difference but got %s", out)
+		}
+
+		if _, err := executeCommand(reconcileCmd(c), "bank", "--statement-date=2024-05-31", "--ending-balance=957.90", "--split=TX1-0"); !errors.Is(err, ErrReadOnly) {
+			t.Fatalf("expected ErrReadOnly but got %v", err)
+		}
+		if states := reconcileStates(t, c); states["TX1-0"] != "n" {
+			t.Fatalf("expected nothing reconciled but got %v", states)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		c, cleanup := setup(t)
+		defer cleanup()
+
+		out, err := executeCommand(reconcileCmd(c), "bank", "--statement-date=2024-05-31", "--ending-balance=-42.10", `--filter=description = "Woolworths"`, "--output=json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var r reconcile.Reconciliation
+		if err := json.Unmarshal([]byte(out), &r); err != nil {
+			t.Fatal(err)
+		}
+		if !r.Reconciled || len(r.Items) != 1 {
+			t.Fatalf("expected reconciled statement of 1 split but got %+v", r)
+		}
+
+		states := reconcileStates(t, c)
+		if states["TX1-0"] != "n" || states["TX2-1"] != "y" {
+			t.Fatalf("unexpected reconcile states %v", states)
+		}
+	})
+
+	t.Run("select by guid", func(t *testing.T) {
+		c, cleanup := setup(t)
+		defer cleanup()
+
+		out, err := executeCommand(reconcileCmd(c), "bank", "--statement-date=2024-05-31", "--ending-balance=957.90", "--split=TX1-0", "--output=json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var r reconcile.Reconciliation
+		if err := json.Unmarshal([]byte(out), &r); err != nil {
+			t.Fatal(err)
+		}
+		if !r.Reconciled || len(r.Items) != 2 {
+			t.Fatalf("expected reconciled statement of 2 splits but got %+v", r)
+		}
+
+		states := reconcileStates(t, c)
+		if states["TX1-0"] != "y" || states["TX2-1"] != "y" || states["TX3-1"] != "n" {
+			t.Fatalf("unexpected reconcile states %v", states)
+		}
+
+		var lastDate int64
+		if err := c.db.QueryRowContext(ctx, `
+SELECT child.int64_val FROM slots frame
+JOIN slots child ON child.obj_guid = frame.guid_val
+WHERE frame.obj_guid='BANKGUID' AND frame.name='reconcile-info' AND child.name='reconcile-info/last-date'
+`).Scan(&lastDate); err != nil {
+			t.Fatal(err)
+		}
+		if lastDate == 0 {
+			t.Fatal("expected last reconcile date to be recorded")
+		}
+	})
+
+	t.Run("interactive", func(t *testing.T) {
+		c, cleanup := setup(t)
+		defer cleanup()
+
+		cmd := reconcileCmd(c)
+		cmd.SetIn(strings.NewReader("1\n\n"))
+		if _, err := executeCommand(cmd, "bank", "--statement-date=2024-05-31", "--ending-balance=957.90", "--interactive"); err != nil {
+			t.Fatal(err)
+		}
+
+		if states := reconcileStates(t, c); states["TX1-0"] != "y" || states["TX2-1"] != "y" {
+			t.Fatalf("unexpected reconcile states %v", states)
+		}
+	})
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"gt/internal/render"
+	"gt/internal/store"
+	"math/big"
+
+	"github.com/spf13/cobra"
+)
+
+func repairCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		output string
+	}
+	var cmd = &cobra.Command{
+		Use:         "repair",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Repair the book problems gt check finds that can be fixed mechanically",
+		Long: `Repair the book problems gt check finds that can be fixed mechanically.
+
+Unbalanced transactions get a balancing split on Imbalance-<currency>,
+split quantities are recomputed from the value when the account is in
+the transaction currency, orphaned accounts are moved under
+Orphan-<currency> and transactions without splits are deleted. All
+fixes are made in one database transaction and each fix is reported.
+Run gt check afterwards for the problems that need a human.`,
+		Example: `  gt --dry-run repair`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var fixes []store.Fix
+
+			_, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				fixes = []store.Fix{}
+				issues, err := txStore.Check(cmd.Context())
+				if err != nil {
+					return err
+				}
+
+				for _, issue := range issues {
+					var fix *store.Fix
+					switch {
+					case issue.Kind == store.IssueUnbalancedTransaction && issue.Table == "transactions":
+						fix, err = repairUnbalancedTransaction(cmd.Context(), txStore, issue.GUID)
+					case issue.Kind == store.IssueValueQuantityMismatch:
+						fix, err = repairSplitQuantity(cmd.Context(), txStore, issue.GUID)
+					case issue.Kind == store.IssueAccountMissingParent:
+						fix, err = repairOrphanAccount(cmd.Context(), txStore, issue.GUID)
+					case issue.Kind == store.IssueEmptyTransaction:
+						fix, err = repairEmptyTransaction(cmd.Context(), txStore, issue.GUID)
+					}
+					if err != nil {
+						return err
+					}
+					if fix != nil {
+						fix.Kind = issue.Kind
+						fixes = append(fixes, *fix)
+					}
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			return r.Render(cmd.OutOrStdout(), fixes)
+		},
+	}
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	return cmd
+}
+
+// repairUnbalancedTransaction balances a transaction with a split on the
+// Imbalance-<currency> account, the same way gnucash does.
+func repairUnbalancedTransaction(ctx context.Context, s *store.Store, guid string) (*store.Fix, error) {
+	transaction, err := s.Transactions.Get(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := new(big.Rat)
+	for _, split := range transaction.Splits {
+		if split.ValueDenom == 0 {
+			return nil, nil
+		}
+		balance.Add(balance, big.NewRat(split.ValueNum, split.ValueDenom))
+	}
+
+	commodity, err := s.Commodities.Get(ctx, transaction.CurrencyGUID)
+	if err != nil {
+		return nil, err
+	}
+
+	value := new(big.Rat).Mul(balance, big.NewRat(-commodity.Fraction, 1))
+	if !value.IsInt() || !value.Num().IsInt64() {
+		return nil, nil
+	}
+
+	account, err := getImbalanceAccount(ctx, s, commodity)
+	if err != nil {
+		return nil, err
+	}
+
+	split := &store.Split{
+		TXGUID:         transaction.GUID,
+		AccountGUID:    account.GUID,
+		ReconcileState: "n",
+		ValueNum:       value.Num().Int64(),
+		ValueDenom:     commodity.Fraction,
+		QuantityNum:    store.ConvertNum(value.Num().Int64(), commodity.Fraction, account.CommoditySCU),
+		QuantityDenom:  account.CommoditySCU,
+	}
+	if err := s.Splits.Insert(ctx, split); err != nil {
+		return nil, err
+	}
+
+	return &store.Fix{
+		Table:   "transactions",
+		GUID:    transaction.GUID,
+		Message: fmt.Sprintf("added split of %s to %s", big.NewRat(split.ValueNum, split.ValueDenom).FloatString(2), account.FullName),
+	}, nil
+}
+
+// repairSplitQuantity sets the quantity of a split to its value, keeping the
+// quantity denominator when the value can be expressed in it.
+func repairSplitQuantity(ctx context.Context, s *store.Store, guid string) (*store.Fix, error) {
+	splits, err := s.Splits.All(ctx, store.NewSplitQuery().Where("guid=?", guid).Limit(1))
+	if err != nil {
+		return nil, err
+	}
+	if len(splits) == 0 {
+		return nil, nil
+	}
+	split := splits[0]
+
+	before := big.NewRat(split.QuantityNum, split.QuantityDenom).FloatString(2)
+	if quantity := new(big.Rat).Mul(big.NewRat(split.ValueNum, split.ValueDenom), big.NewRat(split.QuantityDenom, 1)); quantity.IsInt() {
+		split.QuantityNum = quantity.Num().Int64()
+	} else {
+		split.QuantityNum = split.ValueNum
+		split.QuantityDenom = split.ValueDenom
+	}
+	if err := s.Splits.Update(ctx, split); err != nil {
+		return nil, err
+	}
+
+	return &store.Fix{
+		Table:   "splits",
+		GUID:    split.GUID,
+		Message: fmt.Sprintf("set quantity from %s to %s", before, big.NewRat(split.QuantityNum, split.QuantityDenom).FloatString(2)),
+	}, nil
+}
+
+// repairOrphanAccount moves an account without a parent under the top level
+// Orphan-<currency> account.
+func repairOrphanAccount(ctx context.Context, s *store.Store, guid string) (*store.Fix, error) {
+	account, err := s.Accounts.Get(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+	if account.CommodityGUID == nil {
+		return nil, nil
+	}
+
+	commodity, err := s.Commodities.Get(ctx, *account.CommodityGUID)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := getTopLevelAccount(ctx, s, fmt.Sprintf("Orphan-%s", commodity.Mnemonic), "BANK", commodity)
+	if err != nil {
+		return nil, err
+	}
+
+	account.ParentGUID = &parent.GUID
+	if err := s.Accounts.Update(ctx, account); err != nil {
+		return nil, err
+	}
+
+	return &store.Fix{
+		Table:   "accounts",
+		GUID:    account.GUID,
+		Message: fmt.Sprintf("moved %q under %s", account.Name, parent.FullName),
+	}, nil
+}
+
+// repairEmptyTransaction deletes a transaction without splits.
+func repairEmptyTransaction(ctx context.Context, s *store.Store, guid string) (*store.Fix, error) {
+	transaction, err := s.Transactions.Get(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+	if len(transaction.Splits) > 0 {
+		return nil, nil
+	}
+
+	if err := s.Transactions.Delete(ctx, transaction); err != nil {
+		return nil, err
+	}
+
+	return &store.Fix{
+		Table:   "transactions",
+		GUID:    transaction.GUID,
+		Message: "deleted transaction without splits",
+	}, nil
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"gt/internal/store"
+	"testing"
+)
+
+func TestRepairCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingAccount(ctx, db, t, "ORPHANGUID", "Orphan", "EXPENSE", "MISSINGGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-03", "Coles", "GROCERIESGUID", "BANKGUID", 1000)
+	for _, q := range []string{
+		"UPDATE splits SET value_num=900, quantity_num=900 WHERE guid='TX2-0'",
+		"UPDATE splits SET quantity_num=1 WHERE guid='TX1-0'",
+		"INSERT INTO transactions (guid, currency_guid, num, description) VALUES ('TX3', 'AUDGUID', '', 'Empty')",
+	} {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &cli{db: db}
+
+	t.Run("dry run", func(t *testing.T) {
+		c.dryRun = true
+		defer func() { c.dryRun = false }()
+
+		if _, err := executeCommand(repairCmd(c), "--output=json"); err != nil {
+			t.Fatal(err)
+		}
+
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM transactions WHERE guid='TX3'").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatal("expected dry run to keep the empty transaction")
+		}
+	})
+
+	out, err := executeCommand(repairCmd(c), "--output=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fixes []store.Fix
+	if err := json.Unmarshal([]byte(out), &fixes); err != nil {
+		t.Fatal(err)
+	}
+	if len(fixes) != 4 {
+		t.Fatalf("expected 4 fixes but got %v", fixes)
+	}
+
+	var valueNum int64
+	if err := db.QueryRowContext(ctx, "SELECT splits.value_num FROM splits JOIN accounts ON accounts.guid=splits.account_guid WHERE splits.tx_guid='TX2' AND accounts.name='Imbalance-AUD'").Scan(&valueNum); err != nil {
+		t.Fatal(err)
+	}
+	if valueNum != 100 {
+		t.Fatalf("expected balancing split of 100 but got %d", valueNum)
+	}
+
+	var parentName string
+	if err := db.QueryRowContext(ctx, "SELECT parent.name FROM accounts JOIN accounts parent ON parent.guid=accounts.parent_guid WHERE accounts.guid='ORPHANGUID'").Scan(&parentName); err != nil {
+		t.Fatal(err)
+	}
+	if parentName != "Orphan-AUD" {
+		t.Fatalf("expected orphan to be moved under Orphan-AUD but got %s", parentName)
+	}
+
+	if out, err := executeCommand(checkCmd(c), "--output=json"); err != nil {
+		t.Fatalf("expected a clean book after repair but got %v: %s", err, out)
+	}
+}
@@ -14,7 +14,7 @@ func Execute() {
 	rootCmd := &cobra.Command{
 		Use: "gt",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			return cli.setup()
+			return cli.setup(cmd)
 		},
 	}
 
@@ -26,9 +26,21 @@ func Execute() {
 
 	rootCmd.PersistentFlags().BoolVar(&cli.debug, "debug", false, "Enable debug")
 	rootCmd.PersistentFlags().StringVar(&cli.configFile, "config-file", path.Join(homeDir, ".gt.json"), "Config file")
+	rootCmd.PersistentFlags().BoolVar(&cli.dryRun, "dry-run", false, "Show the changes a command would make without saving them")
+	rootCmd.PersistentFlags().BoolVar(&cli.confirm, "confirm", false, "Show the changes a command would make and ask before saving them")
+	rootCmd.PersistentFlags().BoolVar(&cli.force, "force", false, "Write to the book even when it is locked by gnucash or change the amounts of reconciled splits")
+	rootCmd.PersistentFlags().BoolVar(&cli.readOnly, "read-only", false, "Open the book read-only and refuse commands that write to it")
 
 	rootCmd.AddCommand(accountCmd(cli))
 	rootCmd.AddCommand(transactionCmd(cli))
+	rootCmd.AddCommand(importCmd(cli))
+	rootCmd.AddCommand(rulesCmd(cli))
+	rootCmd.AddCommand(historyCmd(cli))
+	rootCmd.AddCommand(undoCmd(cli))
+	rootCmd.AddCommand(backupCmd(cli))
+	rootCmd.AddCommand(checkCmd(cli))
+	rootCmd.AddCommand(repairCmd(cli))
+	rootCmd.AddCommand(reconcileCmd(cli))
 
 	if err := rootCmd.ExecuteContext(context.TODO()); err != nil {
 		fmt.Fprintf(os.Stderr, err.Error())
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"os"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -44,6 +47,85 @@ func createTestingTables(ctx context.Context, db *sql.DB, t *testing.T) error {
 		return err
 	}
 
+	createTableTransactions := `CREATE TABLE transactions(
+		guid text(32) PRIMARY KEY NOT NULL,
+		currency_guid text(32) NOT NULL,
+		num text(2048) NOT NULL,
+		post_date text(19),
+		enter_date text(19),
+		description text(2048)
+	);`
+	if _, err = db.ExecContext(ctx, createTableTransactions); err != nil {
+		return err
+	}
+
+	createTableSplits := `CREATE TABLE splits(
+		guid text(32) PRIMARY KEY NOT NULL,
+		tx_guid text(32) NOT NULL,
+		account_guid text(32) NOT NULL,
+		memo text(2048) NOT NULL,
+		action text(2048) NOT NULL,
+		reconcile_state text(1) NOT NULL,
+		reconcile_date text(19),
+		value_num bigint NOT NULL,
+		value_denom bigint NOT NULL,
+		quantity_num bigint NOT NULL,
+		quantity_denom bigint NOT NULL,
+		lot_guid text(32)
+	);`
+	if _, err = db.ExecContext(ctx, createTableSplits); err != nil {
+		return err
+	}
+
+	createTableSlots := `CREATE TABLE slots(
+		id integer PRIMARY KEY AUTOINCREMENT NOT NULL,
+		obj_guid text(32) NOT NULL,
+		name text(4096) NOT NULL,
+		slot_type integer NOT NULL,
+		int64_val bigint,
+		string_val text(4096),
+		double_val float8,
+		timespec_val text(19),
+		guid_val text(32),
+		numeric_val_num bigint,
+		numeric_val_denom bigint,
+		gdate_val text(8)
+	);`
+	if _, err = db.ExecContext(ctx, createTableSlots); err != nil {
+		return err
+	}
+
+	createTableCommodities := `CREATE TABLE commodities(
+		guid text(32) PRIMARY KEY NOT NULL,
+		namespace text(2048) NOT NULL,
+		mnemonic text(2048) NOT NULL,
+		fullname text(2048),
+		cusip text(2048),
+		fraction integer NOT NULL,
+		quote_flag integer NOT NULL,
+		quote_source text(2048),
+		quote_tz text(2048)
+	);`
+	if _, err = db.ExecContext(ctx, createTableCommodities); err != nil {
+		return err
+	}
+
+	if _, err = db.ExecContext(ctx, "CREATE TABLE gnclock (Hostname varchar(255), PID int);"); err != nil {
+		return err
+	}
+
+	if _, err = db.ExecContext(ctx,
+		"INSERT INTO commodities (guid, namespace, mnemonic, fullname, fraction, quote_flag) VALUES (?, ?, ?, ?, ?, ?)",
+		"AUDGUID",
+		"CURRENCY",
+		"AUD",
+		"Australian Dollar",
+		100,
+		1,
+	); err != nil {
+		return err
+	}
+
 	rootGUID := "ROOTGUID"
 	expensesGUID := "EXPENSESGUID"
 
@@ -75,3 +157,128 @@ func createTestingTables(ctx context.Context, db *sql.DB, t *testing.T) error {
 
 	return nil
 }
+
+// newTestingDB creates a sqlite database with the testing tables and
+// returns it with a function that removes it.
+func newTestingDB(ctx context.Context, t *testing.T) (*sql.DB, func()) {
+	t.Helper()
+
+	f, _ := os.CreateTemp("", "testdb-*.sqlite")
+	dsn := f.Name()
+	f.Close()
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = createTestingTables(ctx, db, t); err != nil {
+		t.Fatal(err)
+	}
+
+	return db, func() {
+		db.Close()
+		os.Remove(dsn)
+	}
+}
+
+func insertTestingAccount(ctx context.Context, db *sql.DB, t *testing.T, guid, name, accountType, parentGUID string) {
+	t.Helper()
+
+	if _, err := db.ExecContext(ctx,
+		"INSERT INTO accounts (guid, name, account_type, commodity_guid, parent_guid, commodity_scu, non_std_scu) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		guid,
+		name,
+		accountType,
+		"AUDGUID",
+		parentGUID,
+		100,
+		0,
+	); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// insertTestingTransaction inserts a transaction moving valueNum cents from
+// creditGUID to debitGUID.
+func insertTestingTransaction(ctx context.Context, db *sql.DB, t *testing.T, guid, postDate, description, debitGUID, creditGUID string, valueNum int64) {
+	t.Helper()
+
+	if _, err := db.ExecContext(ctx,
+		"INSERT INTO transactions (guid, currency_guid, num, post_date, enter_date, description) VALUES (?, ?, ?, ?, ?, ?)",
+		guid,
+		"AUDGUID",
+		"",
+		postDate+" 10:59:00",
+		postDate+" 10:59:00",
+		description,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	for idx, split := range []struct {
+		accountGUID string
+		valueNum    int64
+	}{
+		{accountGUID: debitGUID, valueNum: valueNum},
+		{accountGUID: creditGUID, valueNum: -valueNum},
+	} {
+		if _, err := db.ExecContext(ctx,
+			"INSERT INTO splits (guid, tx_guid, account_guid, memo, action, reconcile_state, value_num, value_denom, quantity_num, quantity_denom) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			fmt.Sprintf("%s-%d", guid, idx),
+			guid,
+			split.accountGUID,
+			"",
+			"",
+			"n",
+			split.valueNum,
+			100,
+			split.valueNum,
+			100,
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+
+	f, _ := os.CreateTemp("", "testdb-*.sqlite")
+	dsn := f.Name()
+	f.Close()
+	defer os.Remove(dsn)
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = createTestingTables(ctx, db, t); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	configFile, err := os.CreateTemp("", "gt-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(configFile.Name())
+	if _, err := fmt.Fprintf(configFile, `{"gnucash_db_file": %q, "read_only": true}`, dsn); err != nil {
+		t.Fatal(err)
+	}
+	configFile.Close()
+
+	c := &cli{configFile: configFile.Name()}
+	if err := c.setup(updateAccountCmd(c)); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("expected ErrReadOnly but got %v", err)
+	}
+
+	if err := c.setup(listAccountCmd(c)); err != nil {
+		t.Fatal(err)
+	}
+	defer c.db.Close()
+
+	if _, err := c.db.ExecContext(ctx, "DELETE FROM accounts"); err == nil {
+		t.Fatal("expected write to read-only database to fail")
+	}
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"gt/internal/render"
+	"gt/internal/rules"
+	"gt/internal/store"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func rulesCmd(cli *cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "rules",
+		Short: "Categorise transactions with rules",
+	}
+	cmd.AddCommand(applyRulesCmd(cli))
+	return cmd
+}
+
+func applyRulesCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		rulesFile string
+		since     string
+		filter    string
+		output    string
+	}
+	var cmd = &cobra.Command{
+		Use:         "apply",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Apply the rules file to the book",
+		Long: `Apply every rule of the rules file to the transactions of the book.
+
+Rules are tried in order and the first rule that matches a transaction
+is applied to it. All changes are made in one database transaction and
+the number of transactions each rule changed is reported.`,
+		Example: `  gt --dry-run rules apply --since 2024-01-01`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rulesFile := flags.rulesFile
+			if rulesFile == "" {
+				rulesFile = cli.config.RulesFile
+			}
+
+			ruleSet, err := rules.Load(rulesFile)
+			if err != nil {
+				return err
+			}
+
+			q := store.NewTransactionQuery().OrderBy("post_date", false)
+			if flags.since != "" {
+				since, err := time.Parse("2006-01-02", flags.since)
+				if err != nil {
+					return err
+				}
+				q.Where("transactions.post_date >= ?", since.Format("2006-01-02"))
+			}
+
+			hits := make([]rules.Hit, len(ruleSet))
+			for idx, rule := range ruleSet {
+				hits[idx].Rule = rule.Name
+			}
+
+			// Hits are rendered even when --dry-run or --confirm discard the
+			// changes as they show what the rules would do.
+			_, err = cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				resolve := func(guidOrName string) (*store.Account, error) {
+					return getAccount(cmd.Context(), txStore, guidOrName)
+				}
+				for _, rule := range ruleSet {
+					if err := rule.Resolve(resolve); err != nil {
+						return err
+					}
+				}
+
+				if err := applyFilter(cmd.Context(), txStore, q, flags.filter); err != nil {
+					return err
+				}
+
+				transactions, err := txStore.Transactions.All(cmd.Context(), q)
+				if err != nil {
+					return err
+				}
+
+				for _, transaction := range transactions {
+					for idx, rule := range ruleSet {
+						if !rule.Matches(transaction) {
+							continue
+						}
+
+						splits, descriptionChanged := rule.Apply(transaction)
+						for _, split := range splits {
+							if err := txStore.Splits.Update(cmd.Context(), split); err != nil {
+								return err
+							}
+						}
+
+						if descriptionChanged {
+							if err := txStore.Transactions.Update(cmd.Context(), transaction); err != nil {
+								return err
+							}
+						}
+
+						if len(splits) > 0 || descriptionChanged {
+							hits[idx].Transactions++
+						}
+						break
+					}
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			return r.Render(cmd.OutOrStdout(), hits)
+		},
+	}
+	cmd.Flags().StringVar(&flags.rulesFile, "rules-file", "", "Rules file (defaults to rules_file from the config file)")
+	cmd.Flags().StringVar(&flags.since, "since", "", "Only apply rules to transactions posted on or after this date")
+	cmd.Flags().StringVar(&flags.filter, "filter", "", FlagsUsageFilter)
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	return cmd
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gt/internal/rules"
+	"os"
+	"testing"
+)
+
+func TestApplyRulesCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "IMBALANCEGUID", "Imbalance-AUD", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "WOOLWORTHS 1234 SYDNEY", "IMBALANCEGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-03", "ACME PTY LTD", "BANKGUID", "IMBALANCEGUID", 150000)
+
+	rulesFile, err := os.CreateTemp("", "rules-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(rulesFile.Name())
+	if _, err := rulesFile.WriteString(`{
+		"rules": [
+			{
+				"name": "groceries",
+				"match": {"description": "(?i)^woolworths", "max_amount": "500"},
+				"actions": {
+					"move": {"from": "imbalance-aud", "to": "expenses:groceries"},
+					"set_description": "Woolworths"
+				}
+			}
+		]
+	}`); err != nil {
+		t.Fatal(err)
+	}
+	rulesFile.Close()
+
+	c := &cli{db: db}
+	out, err := executeCommand(rulesCmd(c), "apply", "--rules-file", rulesFile.Name(), "--output", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hits []rules.Hit
+	if err := json.Unmarshal([]byte(out), &hits); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hits) != 1 || hits[0].Transactions != 1 {
+		t.Fatalf("expected 1 hit but got %v", hits)
+	}
+
+	var accountGUID, description string
+	if err := db.QueryRowContext(ctx, "SELECT account_guid FROM splits WHERE guid=?", "TX1-0").Scan(&accountGUID); err != nil {
+		t.Fatal(err)
+	}
+	if accountGUID != "GROCERIESGUID" {
+		t.Fatalf("expected split moved to GROCERIESGUID but got %s", accountGUID)
+	}
+
+	if err := db.QueryRowContext(ctx, "SELECT description FROM transactions WHERE guid=?", "TX1").Scan(&description); err != nil {
+		t.Fatal(err)
+	}
+	if description != "Woolworths" {
+		t.Fatalf("expected description Woolworths but got %s", description)
+	}
+}
+
+func TestApplyRulesCmdMoveToOtherCommodity(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "TRAVELGUID", "Travel", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "HOTEL", "BANKGUID", "TRAVELGUID", 4210)
+	if _, err := db.ExecContext(ctx, "UPDATE accounts SET commodity_guid = 'USDGUID' WHERE guid = 'TRAVELGUID'"); err != nil {
+		t.Fatal(err)
+	}
+
+	rulesFile, err := os.CreateTemp("", "rules-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(rulesFile.Name())
+	if _, err := rulesFile.WriteString(`{
+		"rules": [
+			{
+				"name": "travel",
+				"match": {"description": "HOTEL"},
+				"actions": {"move": {"from": "bank", "to": "expenses:travel"}}
+			}
+		]
+	}`); err != nil {
+		t.Fatal(err)
+	}
+	rulesFile.Close()
+
+	c := &cli{db: db}
+	_, err = executeCommand(rulesCmd(c), "apply", "--rules-file", rulesFile.Name())
+	if !errors.Is(err, rules.ErrRuleInvalid) {
+		t.Fatalf("expected ErrRuleInvalid but got %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"gt/internal/render"
+	"gt/internal/store"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var ErrAllocation = errors.New("invalid allocation")
+
+func splitTransactionCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		from      string
+		into      []string
+		output    string
+		shortName bool
+	}
+	var cmd = &cobra.Command{
+		Use:         "split [guid]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Split a split of a transaction into several",
+		Long: `Split a split of a transaction into several.
+
+The split given with --from, by split guid or account, is replaced by
+one split per --into. Each --into is account=amount, account=percent%
+of the split, or account=rest for what the others leave; without rest
+the amounts must add up to the split. Amounts are given without sign,
+the new splits take the sign of the split they replace along with its
+memo and action. The first new split keeps the guid and slots of the
+split it replaces. The new splits are not reconciled and only those on
+the account of the split they replace stay in its lot. Splitting a
+reconciled split requires --force.`,
+		Example: `  gt transaction split <guid> --from expenses:groceries --into expenses:household=12.50 --into expenses:groceries=rest
+  gt transaction split <guid> --from expenses:groceries --into expenses:household=40% --into expenses:groceries=60%`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			guid := args[0]
+			if len(flags.into) == 0 {
+				return fmt.Errorf("%w: --into is required", ErrAllocation)
+			}
+
+			var transaction *store.Transaction
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				var err error
+				transaction, err = getTransaction(cmd.Context(), txStore, guid)
+				if err != nil {
+					return err
+				}
+
+				currency, err := txStore.Commodities.Get(cmd.Context(), transaction.CurrencyGUID)
+				if err != nil {
+					return err
+				}
+
+				from, err := findSplit(cmd.Context(), txStore, transaction, flags.from)
+				if err != nil {
+					return err
+				}
+				if from.ReconcileState == "y" && !cli.force {
+					return fmt.Errorf("%w: %s, use --force to split it", ErrSplitReconciled, from.GUID)
+				}
+				if from.ValueDenom != currency.Fraction {
+					return fmt.Errorf("%w: split value is not in 1/%d of %s", ErrAllocation, currency.Fraction, currency.Mnemonic)
+				}
+
+				accounts := make([]*store.Account, len(flags.into))
+				amounts := make([]string, len(flags.into))
+				for idx, into := range flags.into {
+					name, amount, ok := strings.Cut(into, "=")
+					if !ok {
+						return fmt.Errorf("%w: %q, expected account=amount, account=percent%% or account=rest", ErrAllocation, into)
+					}
+					if accounts[idx], err = getAccount(cmd.Context(), txStore, name); err != nil {
+						return err
+					}
+					if from.Account != nil && !accounts[idx].SameCommodity(from.Account) {
+						return fmt.Errorf("%w: %s is not in the commodity of %s", ErrAllocation, accounts[idx].FullName, from.Account.FullName)
+					}
+					amounts[idx] = amount
+				}
+
+				values, err := allocate(from.ValueNum, amounts, currency.Fraction)
+				if err != nil {
+					return err
+				}
+
+				// The first split keeps the guid of the split it replaces, and
+				// with it its slots such as the online_id an import matches
+				// statement lines on.
+				splits := make([]*store.Split, len(values))
+				var quantity int64
+				for idx, value := range values {
+					split := *from
+					if idx > 0 {
+						split.GUID = ""
+					}
+					split.AccountGUID = accounts[idx].GUID
+					split.Account = accounts[idx]
+					if err := setSplitValue(&split, value, currency, cli.force); err != nil {
+						return err
+					}
+					split.ReconcileState = "n"
+					split.ReconcileDate = nil
+					if split.AccountGUID != from.AccountGUID {
+						split.LogGUID = nil
+					}
+					quantity += split.QuantityNum
+					splits[idx] = &split
+				}
+				// Rounding the quantities of splits in another commodity than
+				// the transaction may leave them off by a little, the last
+				// split takes up the difference.
+				splits[len(splits)-1].QuantityNum += from.QuantityNum - quantity
+
+				if err := txStore.Splits.Update(cmd.Context(), splits[0]); err != nil {
+					return err
+				}
+				for _, split := range splits[1:] {
+					if err := txStore.Splits.Insert(cmd.Context(), split); err != nil {
+						return err
+					}
+				}
+
+				transaction.Splits = append(slices.DeleteFunc(transaction.Splits, func(s *store.Split) bool {
+					return s == from
+				}), splits...)
+				return checkBalanced(transaction)
+			})
+			if err != nil || !committed {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			renderOpts := []render.RendererOptsFunc{render.WithAccountShortName(flags.shortName)}
+			return r.Render(cmd.OutOrStdout(), transaction, renderOpts...)
+		},
+	}
+	cmd.Flags().StringVar(&flags.from, "from", "", "Split to split, by split guid or account")
+	cmd.Flags().StringArrayVar(&flags.into, "into", nil, "New split, as account=amount, account=percent% or account=rest (repeatable)")
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
+	cmd.MarkFlagRequired("from")
+	return cmd
+}
+
+// allocate divides total, a numerator of denom, by amounts which are each a
+// decimal amount, a percentage of total (e.g. 40%) or rest for what the
+// others leave. Amounts are unsigned and take the sign of total.
+func allocate(total int64, amounts []string, denom int64) ([]int64, error) {
+	sign := int64(1)
+	if total < 0 {
+		sign = -1
+	}
+
+	values := make([]int64, len(amounts))
+	rest, lastPercent, percents := -1, -1, int64(0)
+	var allocated int64
+	for idx, amount := range amounts {
+		switch {
+		case amount == "rest":
+			if rest != -1 {
+				return nil, fmt.Errorf("%w: only one split can take the rest", ErrAllocation)
+			}
+			rest = idx
+			continue
+		case strings.HasSuffix(amount, "%"):
+			percent, ok := new(big.Rat).SetString(strings.TrimSuffix(amount, "%"))
+			if !ok || percent.Sign() <= 0 {
+				return nil, fmt.Errorf("%w: invalid percentage %q", ErrAllocation, amount)
+			}
+			value := new(big.Rat).Mul(big.NewRat(total, 100), percent)
+			values[idx] = roundRat(value)
+			lastPercent = idx
+			percents++
+		default:
+			value, err := store.ParseAmount(amount, denom)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrAllocation, err)
+			}
+			if value <= 0 {
+				return nil, fmt.Errorf("%w: amount %q must be positive", ErrAllocation, amount)
+			}
+			values[idx] = sign * value
+		}
+		allocated += values[idx]
+	}
+
+	remaining := total - allocated
+	// Percentages are rounded to denom, the last one takes up the rounding of
+	// all of them.
+	if rest == -1 && lastPercent != -1 && remaining*sign < percents && remaining*sign > -percents {
+		values[lastPercent] += remaining
+		remaining = 0
+	}
+
+	switch {
+	case rest != -1 && remaining*sign <= 0:
+		return nil, fmt.Errorf("%w: nothing is left for the rest", ErrAllocation)
+	case rest != -1:
+		values[rest] = remaining
+	case remaining != 0:
+		return nil, fmt.Errorf("%w: amounts are off by %s", ErrAllocation, big.NewRat(remaining, denom).FloatString(2))
+	}
+
+	return values, nil
+}
+
+// roundRat returns r rounded half away from zero.
+func roundRat(r *big.Rat) int64 {
+	num, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if new(big.Int).Abs(new(big.Int).Mul(rem, big.NewInt(2))).Cmp(r.Denom()) >= 0 {
+		num.Add(num, big.NewInt(int64(r.Sign())))
+	}
+	return num.Int64()
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSplitTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingAccount(ctx, db, t, "HOUSEHOLDGUID", "Household", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-03", "Coles", "GROCERIESGUID", "BANKGUID", 1001)
+
+	if _, err := db.ExecContext(ctx, "UPDATE splits SET memo = 'receipt', reconcile_state = 'c', lot_guid = 'LOT1' WHERE guid = 'TX1-0'"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO slots (obj_guid, name, slot_type, string_val) VALUES ('TX1-0', ?, 4, 'REF1')", onlineIDSlot); err != nil {
+		t.Fatal(err)
+	}
+
+	splits := func(guid string) string {
+		t.Helper()
+
+		rows, err := db.QueryContext(ctx, "SELECT account_guid, memo, reconcile_state, value_num, quantity_num FROM splits WHERE tx_guid = ? ORDER BY value_num", guid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var got []string
+		for rows.Next() {
+			var account, memo, state string
+			var value, quantity int64
+			if err := rows.Scan(&account, &memo, &state, &value, &quantity); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, fmt.Sprintf("%s %q %s %d %d", account, memo, state, value, quantity))
+		}
+		return strings.Join(got, "\n")
+	}
+
+	c := &cli{db: db}
+
+	t.Run("amount and rest", func(t *testing.T) {
+		_, err := executeCommand(splitTransactionCmd(c), "TX1", "--from", "expenses:groceries", "--into", "expenses:household=12.50", "--into", "expenses:groceries=rest")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `BANKGUID "" n -4210 -4210
+HOUSEHOLDGUID "receipt" n 1250 1250
+GROCERIESGUID "receipt" n 2960 2960`
+		if got := splits("TX1"); got != expected {
+			t.Fatalf("expected splits\n%s\nbut got\n%s", expected, got)
+		}
+
+		var account string
+		if err := db.QueryRowContext(ctx, "SELECT account_guid FROM splits WHERE guid = (SELECT obj_guid FROM slots WHERE name = ? AND string_val = 'REF1')", onlineIDSlot).Scan(&account); err != nil {
+			t.Fatalf("expected the online_id slot to be kept: %v", err)
+		}
+		if account != "HOUSEHOLDGUID" {
+			t.Fatalf("expected the online_id slot on the first new split but got it on %s", account)
+		}
+
+		if err := db.QueryRowContext(ctx, "SELECT group_concat(account_guid) FROM splits WHERE lot_guid = 'LOT1'").Scan(&account); err != nil {
+			t.Fatal(err)
+		}
+		if account != "GROCERIESGUID" {
+			t.Fatalf("expected only the split on the lot's account in the lot but got %s", account)
+		}
+	})
+
+	t.Run("percentages", func(t *testing.T) {
+		_, err := executeCommand(splitTransactionCmd(c), "TX2", "--from", "TX2-0", "--into", "expenses:household=40%", "--into", "expenses:groceries=60%")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `BANKGUID "" n -1001 -1001
+HOUSEHOLDGUID "" n 400 400
+GROCERIESGUID "" n 601 601`
+		if got := splits("TX2"); got != expected {
+			t.Fatalf("expected splits\n%s\nbut got\n%s", expected, got)
+		}
+	})
+
+	t.Run("reconciled", func(t *testing.T) {
+		if _, err := db.ExecContext(ctx, "UPDATE splits SET reconcile_state = 'y' WHERE guid = 'TX2-1'"); err != nil {
+			t.Fatal(err)
+		}
+		_, err := executeCommand(splitTransactionCmd(c), "TX2", "--from", "bank", "--into", "bank=rest")
+		if !errors.Is(err, ErrSplitReconciled) {
+			t.Fatalf("expected ErrSplitReconciled but got %v", err)
+		}
+	})
+
+	t.Run("amounts do not add up", func(t *testing.T) {
+		_, err := executeCommand(splitTransactionCmd(c), "TX2", "--from", "expenses:household", "--into", "expenses:household=1", "--into", "expenses:groceries=4")
+		if !errors.Is(err, ErrAllocation) {
+			t.Fatalf("expected ErrAllocation but got %v", err)
+		}
+	})
+}
@@ -1,11 +1,16 @@
 package cli
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"gt/internal/filter"
 	"gt/internal/render"
 	"gt/internal/store"
+	"math/big"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,172 +25,532 @@ func transactionCmd(cli *cli) *cobra.Command {
 	cmd.AddCommand(updateTransactionCmd(cli))
 	cmd.AddCommand(getTransactionCmd(cli))
 	cmd.AddCommand(listTransactionCmd(cli))
+	cmd.AddCommand(editTransactionCmd(cli))
+	cmd.AddCommand(duplicatesTransactionCmd(cli))
+	cmd.AddCommand(exportTransactionCmd(cli))
+	cmd.AddCommand(applyTransactionCmd(cli))
+	cmd.AddCommand(splitTransactionCmd(cli))
+	cmd.AddCommand(voidTransactionCmd(cli))
+	cmd.AddCommand(unvoidTransactionCmd(cli))
+	cmd.AddCommand(reverseTransactionCmd(cli))
+	cmd.AddCommand(duplicateTransactionCmd(cli))
 	return cmd
 }
 
 func bulkUpdateTransactionCmd(cli *cli) *cobra.Command {
 	var flags struct {
-		descriptionLike    string
+		filters            transactionFilterFlags
 		sourceAccount      string
 		destinationAccount string
+		descriptionRegex   string
+		setDescription     string
+		setNum             string
+		setMemo            string
+		setAction          string
+		setReconcileState  string
+		shiftPostDate      int
 		output             string
+		shortName          bool
 	}
 	var cmd = &cobra.Command{
-		Use: "bulk-update",
+		Use:         "bulk-update",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Update every transaction matching the filters",
+		Long: `Update every transaction matching the filters.
+
+Transactions are selected with the same filters as transaction list.
+Splits on --source-account are moved to --destination-account. The
+split memo, action and reconcile state are set on the splits on
+--source-account, or on --account (and its sub-accounts with
+--include-children) when there is no source account, or else on every
+split. With --description-regex only transactions whose description
+matches are changed and $1 style references in --set-description are
+expanded to its capture groups. The number of transactions and splits
+changed is reported.`,
+		Example: `  gt --dry-run transaction bulk-update --account expenses:dining --include-children --set-reconcile-state c
+  gt transaction bulk-update --description-regex '^POS \d+ (.*)$' --set-description '$1'
+  gt transaction bulk-update --filter 'date in 2024-05 and num = "x"' --shift-post-date -1`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			tx, err := cli.db.BeginTx(cmd.Context(), nil)
-			if err != nil {
-				return err
+			var descriptionRe *regexp.Regexp
+			if flags.descriptionRegex != "" {
+				var err error
+				if descriptionRe, err = regexp.Compile(flags.descriptionRegex); err != nil {
+					return err
+				}
 			}
-			defer tx.Rollback()
 
-			s := store.NewStore(cli.db)
-			txStore := s.WithTx(tx)
+			if cmd.Flags().Changed("set-reconcile-state") && !isReconcileState(flags.setReconcileState) {
+				return fmt.Errorf("invalid reconcile state %q, expected one of n, c, y, f or v", flags.setReconcileState)
+			}
 
-			sourceAccount := &store.Account{}
-			if flags.sourceAccount != "" {
-				sourceAccount, err = txStore.Accounts.Get(cmd.Context(), flags.sourceAccount)
-				if err != nil {
-					if errors.Is(err, sql.ErrNoRows) {
-						sourceAccount, err = txStore.Accounts.Get(cmd.Context(), flags.sourceAccount, []store.AccountsOptFunc{store.WithAccountTree(true)}...)
-						if err != nil {
-							return accountError(err)
-						}
-					} else {
-						return accountError(err)
+			var updated []*store.Transaction
+			var splitsUpdated int
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				updated, splitsUpdated = nil, 0
+
+				var err error
+				sourceAccount := &store.Account{}
+				if flags.sourceAccount != "" {
+					sourceAccount, err = getAccount(cmd.Context(), txStore, flags.sourceAccount)
+					if err != nil {
+						return err
 					}
 				}
-			}
 
-			destinationAccount := &store.Account{}
-			if flags.destinationAccount != "" {
-				destinationAccount, err = txStore.Accounts.Get(cmd.Context(), flags.destinationAccount)
+				destinationAccount := &store.Account{}
+				if flags.destinationAccount != "" {
+					destinationAccount, err = getAccount(cmd.Context(), txStore, flags.destinationAccount)
+					if err != nil {
+						return err
+					}
+				}
+
+				q := store.NewTransactionQuery()
+				account, err := flags.filters.apply(cmd.Context(), txStore, q)
 				if err != nil {
-					if errors.Is(err, sql.ErrNoRows) {
-						destinationAccount, err = txStore.Accounts.Get(cmd.Context(), flags.destinationAccount, []store.AccountsOptFunc{store.WithAccountTree(true)}...)
+					return err
+				}
+
+				// splitAccounts are the accounts whose splits get the split
+				// level fields, nil meaning every split.
+				var splitAccounts map[string]bool
+				switch {
+				case sourceAccount.GUID != "":
+					splitAccounts = map[string]bool{sourceAccount.GUID: true}
+				case account != nil:
+					splitAccounts = map[string]bool{account.GUID: true}
+					if flags.filters.includeChildren {
+						tree, err := txStore.AccountTree(cmd.Context())
 						if err != nil {
-							return accountError(err)
+							return err
+						}
+						for _, descendant := range tree.Descendants(account.GUID) {
+							splitAccounts[descendant.GUID] = true
 						}
-					} else {
-						return accountError(err)
 					}
 				}
-			}
 
-			q := store.NewTransactionQuery()
-			if flags.descriptionLike != "" {
-				q.Where("transactions.description LIKE ?", flags.descriptionLike)
-			}
+				transactions, err := txStore.Transactions.All(cmd.Context(), q)
+				if err != nil {
+					return err
+				}
 
-			transactions, err := txStore.Transactions.All(cmd.Context(), q)
-			if err != nil {
-				return err
-			}
+				for _, transaction := range transactions {
+					if descriptionRe != nil && (transaction.Description == nil || !descriptionRe.MatchString(*transaction.Description)) {
+						continue
+					}
 
-			for _, transaction := range transactions {
-				for _, split := range transaction.Splits {
-					if split.AccountGUID == sourceAccount.GUID && destinationAccount.GUID != "" {
-						split.AccountGUID = destinationAccount.GUID
-						split.Account = destinationAccount
+					transactionChanged := false
+					if cmd.Flags().Changed("set-description") {
+						description := flags.setDescription
+						if descriptionRe != nil {
+							match := descriptionRe.FindStringSubmatchIndex(*transaction.Description)
+							description = string(descriptionRe.ExpandString(nil, description, *transaction.Description, match))
+						}
+						if transaction.Description == nil || *transaction.Description != description {
+							transaction.Description = &description
+							transactionChanged = true
+						}
+					}
 
-						if err := txStore.Splits.Update(cmd.Context(), split); err != nil {
+					if cmd.Flags().Changed("set-num") && transaction.Num != flags.setNum {
+						transaction.Num = flags.setNum
+						transactionChanged = true
+					}
+
+					if flags.shiftPostDate != 0 && transaction.PostDate != nil {
+						postDate := transaction.PostDate.AddDate(0, 0, flags.shiftPostDate)
+						transaction.PostDate = &postDate
+						transactionChanged = true
+					}
+
+					if transactionChanged {
+						if err := txStore.Transactions.Update(cmd.Context(), transaction); err != nil {
 							return err
 						}
 					}
+
+					splitsChanged := 0
+					for _, split := range transaction.Splits {
+						changed := false
+						if split.AccountGUID == sourceAccount.GUID && destinationAccount.GUID != "" {
+							split.AccountGUID = destinationAccount.GUID
+							split.Account = destinationAccount
+							changed = true
+						}
+
+						if splitAccounts == nil || splitAccounts[split.AccountGUID] || changed {
+							if cmd.Flags().Changed("set-memo") && split.Memo != flags.setMemo {
+								split.Memo = flags.setMemo
+								changed = true
+							}
+							if cmd.Flags().Changed("set-action") && split.Action != flags.setAction {
+								split.Action = flags.setAction
+								changed = true
+							}
+							if cmd.Flags().Changed("set-reconcile-state") && split.ReconcileState != flags.setReconcileState {
+								split.ReconcileState = flags.setReconcileState
+								split.ReconcileDate = nil
+								if split.ReconcileState == "y" {
+									reconcileDate := time.Now().UTC().Truncate(time.Second)
+									split.ReconcileDate = &reconcileDate
+								}
+								changed = true
+							}
+						}
+
+						if changed {
+							if err := txStore.Splits.Update(cmd.Context(), split); err != nil {
+								return err
+							}
+							splitsChanged++
+						}
+					}
+
+					if transactionChanged || splitsChanged > 0 {
+						updated = append(updated, transaction)
+						splitsUpdated += splitsChanged
+					}
 				}
-			}
 
-			if err := tx.Commit(); err != nil {
+				return nil
+			})
+			if err != nil {
 				return err
 			}
 
+			switch {
+			case committed:
+				fmt.Fprintf(cmd.ErrOrStderr(), "updated %d transactions and %d splits\n", len(updated), splitsUpdated)
+			case cli.dryRun:
+				fmt.Fprintf(cmd.ErrOrStderr(), "would update %d transactions and %d splits\n", len(updated), splitsUpdated)
+			}
+			if !committed {
+				return nil
+			}
+
 			r, err := render.New(flags.output)
 			if err != nil {
 				return err
 			}
 
-			return r.Render(cmd.OutOrStderr(), transactions)
+			renderOpts := []render.RendererOptsFunc{render.WithAccountShortName(flags.shortName)}
+			return r.Render(cmd.OutOrStdout(), updated, renderOpts...)
 		},
 	}
+	flags.filters.addFlags(cmd)
 	cmd.Flags().StringVar(&flags.sourceAccount, "source-account", "", "Source Account GUID or Full Account Name")
 	cmd.Flags().StringVar(&flags.destinationAccount, "destination-account", "", "Destination Account GUID or Full Account Name")
-	cmd.Flags().StringVar(&flags.descriptionLike, "description-like", "", "Description like")
+	cmd.Flags().StringVar(&flags.descriptionRegex, "description-regex", "", "Only update transactions whose description matches this regular expression")
+	cmd.Flags().StringVar(&flags.setDescription, "set-description", "", "Set the description, expanding $1 style references to --description-regex groups")
+	cmd.Flags().StringVar(&flags.setNum, "set-num", "", "Set the transaction number")
+	cmd.Flags().StringVar(&flags.setMemo, "set-memo", "", "Set the split memo")
+	cmd.Flags().StringVar(&flags.setAction, "set-action", "", "Set the split action")
+	cmd.Flags().StringVar(&flags.setReconcileState, "set-reconcile-state", "", "Set the split reconcile state (n, c, y, f or v)")
+	cmd.Flags().IntVar(&flags.shiftPostDate, "shift-post-date", 0, "Move the post date by this many days, negative moves it earlier")
 	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
 	return cmd
 }
 
+// transactionFilterFlags are the flags of the commands that select
+// transactions.
+type transactionFilterFlags struct {
+	account         string
+	includeChildren bool
+	startPostDate   string
+	endPostDate     string
+	descriptionLike string
+	memoLike        string
+	reconcileState  string
+	minAmount       string
+	maxAmount       string
+	voided          string
+	filter          string
+}
+
+func (f *transactionFilterFlags) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.account, "account", "", "Account GUID or Full Account Name")
+	cmd.Flags().BoolVar(&f.includeChildren, "include-children", false, "Include transactions of the account's sub-accounts")
+	cmd.Flags().StringVar(&f.startPostDate, "start-post-date", "", "Start Post Date")
+	cmd.Flags().StringVar(&f.endPostDate, "end-post-date", "", "End Post Date")
+	cmd.Flags().StringVar(&f.descriptionLike, "description-like", "", "Description like")
+	cmd.Flags().StringVar(&f.memoLike, "memo-like", "", "Memo of any split like")
+	cmd.Flags().StringVar(&f.reconcileState, "reconcile-state", "", "Reconcile state of any split (n, c, y, f or v)")
+	cmd.Flags().StringVar(&f.minAmount, "min-amount", "", "Minimum transaction amount")
+	cmd.Flags().StringVar(&f.maxAmount, "max-amount", "", "Maximum transaction amount")
+	cmd.Flags().StringVar(&f.voided, "voided", "include", "Voided transactions (include, exclude or only)")
+	cmd.Flags().StringVar(&f.filter, "filter", "", FlagsUsageFilter)
+}
+
+// apply adds the filters to q and returns the --account account, if any.
+func (f *transactionFilterFlags) apply(ctx context.Context, s *store.Store, q *store.TransactionQuery) (*store.Account, error) {
+	var account *store.Account
+	if f.account != "" {
+		var err error
+		account, err = getAccount(ctx, s, f.account)
+		if err != nil {
+			return nil, err
+		}
+		q.WhereAccount(account.GUID, f.includeChildren)
+	}
+
+	if f.startPostDate != "" {
+		startPostDate, err := time.Parse("2006-01-02", f.startPostDate)
+		if err != nil {
+			return nil, err
+		}
+		q.Where("transactions.post_date > ?", startPostDate.Format("2006-01-02"))
+	}
+
+	if f.endPostDate != "" {
+		endPostDate, err := time.Parse("2006-01-02", f.endPostDate)
+		if err != nil {
+			return nil, err
+		}
+		q.Where("transactions.post_date<=?", endPostDate.Format("2006-01-02"))
+	}
+
+	if f.descriptionLike != "" {
+		q.Where("transactions.description LIKE ?", f.descriptionLike)
+	}
+
+	if f.memoLike != "" {
+		q.Where("transactions.guid IN (SELECT tx_guid FROM splits WHERE memo LIKE ?)", f.memoLike)
+	}
+
+	for _, c := range []filter.Comparison{
+		{Field: filter.FieldReconcile, Op: filter.OpEq, Value: f.reconcileState},
+		{Field: filter.FieldAmount, Op: filter.OpGe, Value: f.minAmount},
+		{Field: filter.FieldAmount, Op: filter.OpLe, Value: f.maxAmount},
+	} {
+		if c.Value == "" {
+			continue
+		}
+		clause, args, err := filter.Compile(c, nil)
+		if err != nil {
+			return nil, err
+		}
+		q.Where(clause, args...)
+	}
+
+	voided := "transactions.guid IN (SELECT obj_guid FROM slots WHERE name=?)"
+	switch f.voided {
+	case "", "include":
+	case "exclude":
+		q.Where("NOT "+voided, voidReasonSlot)
+	case "only":
+		q.Where(voided, voidReasonSlot)
+	default:
+		return nil, fmt.Errorf("invalid voided %q, expected one of include, exclude or only", f.voided)
+	}
+
+	if err := applyFilter(ctx, s, q, f.filter); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func isReconcileState(s string) bool {
+	return len(s) == 1 && strings.Contains("ncyfv", s)
+}
+
 func updateTransactionCmd(cli *cli) *cobra.Command {
 	var flags struct {
 		sourceAccount      string
 		destinationAccount string
+		description        string
+		postDate           string
+		num                string
+		memos              []string
+		amount             string
+		addSplits          []string
+		removeSplits       []string
 		output             string
+		shortName          bool
 	}
 	var cmd = &cobra.Command{
-		Use: "update",
+		Use:         "update",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Update a transaction",
+		Long: `Update a transaction.
+
+Splits are identified by their guid or by the guid or full name of
+their account. --amount sets the amount of a transaction with two splits
+on both of them. Splits are removed before --amount is applied and
+added after it, with a negative amount for a credit. The transaction
+must balance after the update or nothing is changed. The amount of a
+reconciled split is only changed with --force.`,
+		Example: `  gt transaction update <guid> --description "Woolworths" --post-date 2024-05-02 --amount 42.10
+  gt transaction update <guid> --memo expenses:groceries="milk and bread"
+  gt transaction update <guid> --remove-split expenses:groceries --add-split expenses:groceries=30 --add-split expenses:household=12.10`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return ErrTransactionMissing
 			}
 			guid := args[0]
 
-			tx, err := cli.db.BeginTx(cmd.Context(), nil)
-			if err != nil {
-				return err
-			}
-			defer tx.Rollback()
-
-			s := store.NewStore(cli.db)
-			txStore := s.WithTx(tx)
-
-			transaction, err := txStore.Transactions.Get(cmd.Context(), guid)
-			if err != nil {
-				if errors.Is(err, sql.ErrNoRows) {
-					return ErrTransactionNotFound
+			var postDate *time.Time
+			if flags.postDate != "" {
+				t, err := time.Parse("2006-01-02", flags.postDate)
+				if err != nil {
+					return err
 				}
-				return err
+				t = store.DatePost(t)
+				postDate = &t
 			}
 
-			sourceAccount := &store.Account{}
-			if flags.sourceAccount != "" {
-				sourceAccount, err = txStore.Accounts.Get(cmd.Context(), flags.sourceAccount)
+			var transaction *store.Transaction
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				var err error
+				transaction, err = txStore.Transactions.Get(cmd.Context(), guid)
 				if err != nil {
 					if errors.Is(err, sql.ErrNoRows) {
-						sourceAccount, err = txStore.Accounts.Get(cmd.Context(), flags.sourceAccount, []store.AccountsOptFunc{store.WithAccountTree(true)}...)
-						if err != nil {
-							return ErrAccountDoesNotExist
-						}
-					} else {
-						return ErrAccountDoesNotExist
+						return ErrTransactionNotFound
 					}
+					return err
 				}
-			}
 
-			destinationAccount := &store.Account{}
-			if flags.destinationAccount != "" {
-				destinationAccount, err = txStore.Accounts.Get(cmd.Context(), flags.destinationAccount)
+				currency, err := txStore.Commodities.Get(cmd.Context(), transaction.CurrencyGUID)
 				if err != nil {
-					if errors.Is(err, sql.ErrNoRows) {
-						destinationAccount, err = txStore.Accounts.Get(cmd.Context(), flags.destinationAccount, []store.AccountsOptFunc{store.WithAccountTree(true)}...)
-						if err != nil {
-							return ErrAccountDoesNotExist
+					return err
+				}
+
+				sourceAccount := &store.Account{}
+				if flags.sourceAccount != "" {
+					sourceAccount, err = getAccount(cmd.Context(), txStore, flags.sourceAccount)
+					if err != nil {
+						return err
+					}
+				}
+
+				destinationAccount := &store.Account{}
+				if flags.destinationAccount != "" {
+					destinationAccount, err = getAccount(cmd.Context(), txStore, flags.destinationAccount)
+					if err != nil {
+						return err
+					}
+				}
+
+				for _, split := range transaction.Splits {
+					if split.AccountGUID == sourceAccount.GUID && destinationAccount.GUID != "" {
+						split.AccountGUID = destinationAccount.GUID
+						split.Account = destinationAccount
+						if err := txStore.Splits.Update(cmd.Context(), split); err != nil {
+							return err
 						}
-					} else {
-						return ErrAccountDoesNotExist
 					}
 				}
-			}
 
-			for _, split := range transaction.Splits {
-				if split.AccountGUID == sourceAccount.GUID && destinationAccount.GUID != "" {
-					split.AccountGUID = destinationAccount.GUID
-					split.Account = destinationAccount
+				for _, ref := range flags.removeSplits {
+					split, err := findSplit(cmd.Context(), txStore, transaction, ref)
+					if err != nil {
+						return err
+					}
+					if err := txStore.Splits.Delete(cmd.Context(), split); err != nil {
+						return err
+					}
+					transaction.Splits = slices.DeleteFunc(transaction.Splits, func(s *store.Split) bool {
+						return s == split
+					})
+				}
+
+				if flags.amount != "" {
+					if len(transaction.Splits) != 2 {
+						return fmt.Errorf("--amount needs a transaction with two splits but it has %d", len(transaction.Splits))
+					}
+					amount, err := store.ParseAmount(flags.amount, currency.Fraction)
+					if err != nil {
+						return err
+					}
+					if amount < 0 {
+						amount = -amount
+					}
+
+					debit, credit := transaction.Splits[0], transaction.Splits[1]
+					if debit.ValueNum < 0 || debit.ValueNum == 0 && credit.ValueNum > 0 {
+						debit, credit = credit, debit
+					}
+					if err := setSplitValue(debit, amount, currency, cli.force); err != nil {
+						return err
+					}
+					if err := setSplitValue(credit, -amount, currency, cli.force); err != nil {
+						return err
+					}
+					for _, split := range transaction.Splits {
+						if err := txStore.Splits.Update(cmd.Context(), split); err != nil {
+							return err
+						}
+					}
+				}
+
+				for _, addSplit := range flags.addSplits {
+					name, value, ok := strings.Cut(addSplit, "=")
+					if !ok {
+						return fmt.Errorf("invalid split %q, expected account=amount", addSplit)
+					}
+					account, err := getAccount(cmd.Context(), txStore, name)
+					if err != nil {
+						return err
+					}
+					valueNum, err := store.ParseAmount(value, currency.Fraction)
+					if err != nil {
+						return err
+					}
+
+					split := &store.Split{
+						TXGUID:         transaction.GUID,
+						AccountGUID:    account.GUID,
+						ReconcileState: "n",
+						Account:        account,
+					}
+					if err := setSplitValue(split, valueNum, currency, cli.force); err != nil {
+						return err
+					}
+					if err := txStore.Splits.Insert(cmd.Context(), split); err != nil {
+						return err
+					}
+					transaction.Splits = append(transaction.Splits, split)
+				}
+
+				for _, memo := range flags.memos {
+					ref, value, ok := strings.Cut(memo, "=")
+					if !ok {
+						return fmt.Errorf("invalid memo %q, expected split=memo", memo)
+					}
+					split, err := findSplit(cmd.Context(), txStore, transaction, ref)
+					if err != nil {
+						return err
+					}
+					split.Memo = value
 					if err := txStore.Splits.Update(cmd.Context(), split); err != nil {
 						return err
 					}
 				}
-			}
 
-			if err := tx.Commit(); err != nil {
+				transactionChanged := false
+				if cmd.Flags().Changed("description") {
+					transaction.Description = &flags.description
+					transactionChanged = true
+				}
+				if cmd.Flags().Changed("num") {
+					transaction.Num = flags.num
+					transactionChanged = true
+				}
+				if postDate != nil {
+					transaction.PostDate = postDate
+					transactionChanged = true
+				}
+				if transactionChanged {
+					if err := txStore.Transactions.Update(cmd.Context(), transaction); err != nil {
+						return err
+					}
+				}
+
+				return checkBalanced(transaction)
+			})
+			if err != nil || !committed {
 				return err
 			}
 
@@ -194,138 +559,184 @@ func updateTransactionCmd(cli *cli) *cobra.Command {
 				return err
 			}
 
-			return r.Render(cmd.OutOrStdout(), transaction)
+			renderOpts := []render.RendererOptsFunc{render.WithAccountShortName(flags.shortName)}
+			return r.Render(cmd.OutOrStdout(), transaction, renderOpts...)
 		},
 	}
 	cmd.Flags().StringVar(&flags.sourceAccount, "source-account", "", "Source Account GUID or Full Account Name")
 	cmd.Flags().StringVar(&flags.destinationAccount, "destination-account", "", "Destination Account GUID or Full Account Name")
+	cmd.Flags().StringVar(&flags.description, "description", "", "Set the description")
+	cmd.Flags().StringVar(&flags.postDate, "post-date", "", "Set the post date (YYYY-MM-DD)")
+	cmd.Flags().StringVar(&flags.num, "num", "", "Set the transaction number")
+	cmd.Flags().StringArrayVar(&flags.memos, "memo", nil, "Set the memo of a split, as split=memo (repeatable)")
+	cmd.Flags().StringVar(&flags.amount, "amount", "", "Set the amount of a transaction with two splits")
+	cmd.Flags().StringArrayVar(&flags.addSplits, "add-split", nil, "Add a split, as account=amount (repeatable)")
+	cmd.Flags().StringArrayVar(&flags.removeSplits, "remove-split", nil, "Remove a split by split guid or account (repeatable)")
 	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
 	return cmd
 }
 
+// findSplit returns the split of transaction identified by ref, which is a
+// split guid or the guid or full name of the account of a single split.
+func findSplit(ctx context.Context, s *store.Store, transaction *store.Transaction, ref string) (*store.Split, error) {
+	for _, split := range transaction.Splits {
+		if split.GUID == ref {
+			return split, nil
+		}
+	}
+
+	account, err := getAccount(ctx, s, ref)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSplitNotFound, ref)
+	}
+
+	var found *store.Split
+	for _, split := range transaction.Splits {
+		if split.AccountGUID != account.GUID {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("transaction has more than one split on %s, use the split guid", account.FullName)
+		}
+		found = split
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSplitNotFound, ref)
+	}
+	return found, nil
+}
+
+// setSplitValue sets the value of split to valueNum in the transaction
+// currency and its quantity to match, at the split's existing price rounded
+// to the quantity denominator when its account is in another commodity. The
+// amount of a reconciled split is only changed with force.
+func setSplitValue(split *store.Split, valueNum int64, currency *store.Commodity, force bool) error {
+	if split.ReconcileState == "y" && !force && (split.ValueDenom == 0 || big.NewRat(split.ValueNum, split.ValueDenom).Cmp(big.NewRat(valueNum, currency.Fraction)) != 0) {
+		return fmt.Errorf("%w: %s, use --force to change its amount", ErrSplitReconciled, split.GUID)
+	}
+
+	account := split.Account
+	switch {
+	case account != nil && account.CommodityGUID != nil && *account.CommodityGUID == currency.GUID:
+		quantity := new(big.Rat).Mul(big.NewRat(valueNum, currency.Fraction), big.NewRat(account.CommoditySCU, 1))
+		if !quantity.IsInt() {
+			return fmt.Errorf("amount has more precision than account %s allows", account.FullName)
+		}
+		split.QuantityNum = quantity.Num().Int64()
+		split.QuantityDenom = account.CommoditySCU
+	case split.ValueNum != 0 && split.ValueDenom != 0 && split.QuantityDenom != 0:
+		price := new(big.Rat).Quo(big.NewRat(split.QuantityNum, split.QuantityDenom), big.NewRat(split.ValueNum, split.ValueDenom))
+		quantity := new(big.Rat).Mul(big.NewRat(valueNum, currency.Fraction), price)
+		split.QuantityNum = store.RatToNum(quantity, split.QuantityDenom)
+	default:
+		return fmt.Errorf("can not set the amount of a split on %s, which is not in %s", split.AccountGUID, currency.Mnemonic)
+	}
+
+	split.ValueNum = valueNum
+	split.ValueDenom = currency.Fraction
+	return nil
+}
+
+// checkBalanced returns ErrTransactionUnbalanced unless the split values of
+// transaction add up to zero.
+func checkBalanced(transaction *store.Transaction) error {
+	if len(transaction.Splits) == 0 {
+		return fmt.Errorf("%w: transaction has no splits", ErrTransactionUnbalanced)
+	}
+
+	balance := new(big.Rat)
+	for _, split := range transaction.Splits {
+		if split.ValueDenom == 0 {
+			return fmt.Errorf("%w: split %s has no value", ErrTransactionUnbalanced, split.GUID)
+		}
+		balance.Add(balance, big.NewRat(split.ValueNum, split.ValueDenom))
+	}
+	if balance.Sign() != 0 {
+		return fmt.Errorf("%w: splits are off by %s", ErrTransactionUnbalanced, balance.FloatString(2))
+	}
+	return nil
+}
+
 func listTransactionCmd(cli *cli) *cobra.Command {
 	var flags struct {
-		account         string
+		filters         transactionFilterFlags
 		limit           int
-		startPostDate   string
-		endPostDate     string
-		descriptionLike string
 		output          string
 		orderByPostDate bool
 		orderDescending bool
 		includeTotals   bool
+		shortName       bool
 	}
 	var cmd = &cobra.Command{
 		Use: "list",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var err error
 			s := store.NewStore(cli.db)
 			transactionQuery := store.NewTransactionQuery()
 
-			var account *store.Account
-			if flags.account != "" {
-				account, err = s.Accounts.Get(cmd.Context(), flags.account)
-				if err != nil {
-					if errors.Is(err, sql.ErrNoRows) {
-						account, err = s.Accounts.Get(cmd.Context(), flags.account, []store.AccountsOptFunc{store.WithAccountTree(true)}...)
-						if err != nil {
-							return ErrAccountDoesNotExist
-						}
-					} else {
-						return ErrAccountDoesNotExist
-					}
-				}
-			}
-
 			transactionQuery.Limit(flags.limit)
 
 			if flags.orderByPostDate {
 				transactionQuery.OrderBy("post_date", flags.orderDescending)
 			}
 
-			if flags.startPostDate != "" {
-				startPostDate, err := time.Parse("2006-01-02", flags.startPostDate)
-				if err != nil {
-					return err
-				}
-				transactionQuery.Where("transactions.post_date > ?", startPostDate.Format("2006-01-02"))
+			account, err := flags.filters.apply(cmd.Context(), &s, transactionQuery)
+			if err != nil {
+				return err
 			}
 
-			if flags.endPostDate != "" {
-				endPostDate, err := time.Parse("2006-01-02", flags.endPostDate)
-				if err != nil {
-					return err
-				}
-				transactionQuery.Where("transactions.post_date<=?", endPostDate.Format("2006-01-02"))
+			transactions, err := s.Transactions.All(cmd.Context(), transactionQuery)
+			if err != nil {
+				return err
 			}
 
-			if flags.descriptionLike != "" {
-				transactionQuery.Where("transactions.description LIKE ?", flags.descriptionLike)
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
 			}
 
-			var transactions []*store.Transaction
-			if account != nil {
-				// NOTE(rene): If account is not nil, user is wanting to list
-				// transactions by account. To do this, we must find all splits with
-				// account_guid == account then return all transactions for found
-				// splits.
-				splits, err := s.Splits.All(cmd.Context(), store.NewSplitQuery().Where("account_guid=?", account.GUID))
-				if err != nil {
-					return err
-				}
-
-				txGUIDs := make([]string, 0, len(splits))
-				seenGUIDs := make(map[string]bool)
-				for _, split := range splits {
-					if !seenGUIDs[split.TXGUID] {
-						txGUIDs = append(txGUIDs, split.TXGUID)
-						seenGUIDs[split.TXGUID] = true
-					}
-				}
-
-				placeholders := make([]string, len(txGUIDs))
-				args := make([]any, len(txGUIDs))
-				for i, guid := range txGUIDs {
-					placeholders[i] = "?"
-					args[i] = guid
-				}
-
-				transactions, err = s.Transactions.All(cmd.Context(), transactionQuery.Copy().Where(fmt.Sprintf("guid IN (%s)", strings.Join(placeholders, ",")), args...))
-				if err != nil {
-					return err
-				}
-			} else {
-				transactions, err = s.Transactions.All(cmd.Context(), transactionQuery)
+			renderOpts := []render.RendererOptsFunc{
+				render.WithIncludeTotals(flags.includeTotals),
+				render.WithAccountShortName(flags.shortName),
+			}
+			if account != nil && flags.filters.includeChildren {
+				tree, err := s.AccountTree(cmd.Context())
 				if err != nil {
 					return err
 				}
+				renderOpts = append(renderOpts, render.WithTotalsRollup(childRollup(tree, account)))
 			}
-
-			r, err := render.New(flags.output)
-			if err != nil {
-				return err
-			}
-
-			renderOpts := []render.RendererOptsFunc{render.WithIncludeTotals(flags.includeTotals)}
 			return r.Render(cmd.OutOrStdout(), transactions, renderOpts...)
 		},
 	}
 
 	cmd.Flags().IntVar(&flags.limit, "limit", 50, "Limit")
-	cmd.Flags().StringVar(&flags.account, "account", "", "Account GUID")
-	cmd.Flags().StringVar(&flags.startPostDate, "start-post-date", "", "Start Post Date")
-	cmd.Flags().StringVar(&flags.endPostDate, "end-post-date", "", "Start Post Date")
+	flags.filters.addFlags(cmd)
 	cmd.Flags().BoolVar(&flags.orderByPostDate, "order-by-post-date", true, "Order by Post Date")
 	cmd.Flags().BoolVar(&flags.orderDescending, "order-descending", false, "Order Descending")
-	cmd.Flags().StringVar(&flags.descriptionLike, "description-like", "", "Description like")
 	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
 	cmd.Flags().BoolVar(&flags.includeTotals, "include-totals", true, FlagsUsageIncludeTotals)
+	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
 	return cmd
 }
 
+// childRollup maps every account below account to the child of account it
+// belongs to so totals are rolled up per child.
+func childRollup(tree *store.AccountTree, account *store.Account) map[string]*store.Account {
+	rollup := map[string]*store.Account{account.GUID: account}
+	for _, child := range tree.Children(account.GUID) {
+		rollup[child.GUID] = child
+		for _, descendant := range tree.Descendants(child.GUID) {
+			rollup[descendant.GUID] = child
+		}
+	}
+	return rollup
+}
+
 func getTransactionCmd(cli *cli) *cobra.Command {
 	var flags struct {
-		output string
+		output    string
+		shortName bool
 	}
 	var cmd = &cobra.Command{
 		Use: "get",
@@ -346,9 +757,11 @@ func getTransactionCmd(cli *cli) *cobra.Command {
 				return err
 			}
 
-			return r.Render(cmd.OutOrStdout(), transaction)
+			renderOpts := []render.RendererOptsFunc{render.WithAccountShortName(flags.shortName)}
+			return r.Render(cmd.OutOrStdout(), transaction, renderOpts...)
 		},
 	}
 	cmd.Flags().StringVar(&flags.output, "output", "table", "Output format")
+	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
 	return cmd
 }
@@ -0,0 +1,416 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gt/internal/filter"
+	"gt/internal/store"
+	"strings"
+	"testing"
+)
+
+func TestGetTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+
+	c := &cli{db: db}
+	out, err := executeCommand(getTransactionCmd(c), "TX1", "--output=json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var transaction store.Transaction
+	if err := json.Unmarshal([]byte(out), &transaction); err != nil {
+		t.Fatal(err)
+	}
+
+	account := transaction.Splits[0].Account
+	if account.FullName != "Expenses:Groceries" {
+		t.Fatalf("expected full name Expenses:Groceries but got %s", account.FullName)
+	}
+	if account.CommodityGUID == nil || *account.CommodityGUID != "AUDGUID" {
+		t.Fatalf("expected commodity AUDGUID but got %v", account.CommodityGUID)
+	}
+	if account.ParentGUID == nil || *account.ParentGUID != "EXPENSESGUID" {
+		t.Fatalf("expected parent EXPENSESGUID but got %v", account.ParentGUID)
+	}
+
+	out, err = executeCommand(getTransactionCmd(c), "TX1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Expenses:Groceries") {
+		t.Fatalf("expected full account name in table but got %s", out)
+	}
+
+	out, err = executeCommand(getTransactionCmd(c), "TX1", "--short-name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "Expenses:Groceries") || !strings.Contains(out, "Groceries") {
+		t.Fatalf("expected short account name in table but got %s", out)
+	}
+}
+
+func TestListTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "DININGGUID", "Dining", "EXPENSE", "EXPENSESGUID")
+	insertTestingAccount(ctx, db, t, "PIZZAGUID", "Pizza", "EXPENSE", "DININGGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Pizza Hut", "PIZZAGUID", "BANKGUID", 2500)
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-03", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX3", "2024-05-04", "Transfer", "BANKGUID", "ROOTGUID", 10000)
+
+	c := &cli{db: db}
+
+	t.Run("account only", func(t *testing.T) {
+		out, err := executeCommand(listTransactionCmd(c), "--account=expenses", "--output=json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var transactions []*store.Transaction
+		if err := json.Unmarshal([]byte(out), &transactions); err != nil {
+			t.Fatal(err)
+		}
+		if len(transactions) != 0 {
+			t.Fatalf("expected no transactions but got %d", len(transactions))
+		}
+	})
+
+	t.Run("include children", func(t *testing.T) {
+		out, err := executeCommand(listTransactionCmd(c), "--account=expenses", "--include-children", "--output=json")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var transactions []*store.Transaction
+		if err := json.Unmarshal([]byte(out), &transactions); err != nil {
+			t.Fatal(err)
+		}
+		if len(transactions) != 2 || transactions[0].GUID != "TX1" || transactions[1].GUID != "TX2" {
+			t.Fatalf("expected TX1 and TX2 but got %v", transactions)
+		}
+	})
+
+	t.Run("totals rolled up per child", func(t *testing.T) {
+		out, err := executeCommand(listTransactionCmd(c), "--account=expenses", "--include-children")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, totals, ok := strings.Cut(out, "TOTALS")
+		if !ok {
+			t.Fatalf("expected totals but got %s", out)
+		}
+		if strings.Contains(totals, "Pizza") || !strings.Contains(totals, "Expenses:Dining") || !strings.Contains(totals, "Expenses:Groceries") {
+			t.Fatalf("expected totals per child account but got %s", totals)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		if _, err := db.ExecContext(ctx, "UPDATE splits SET memo = 'Work lunch' WHERE guid = 'TX1-0'"); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range []struct {
+			filter   string
+			expected []string
+		}{
+			{filter: `account ~ "expenses:dining*"`, expected: []string{"TX1"}},
+			{filter: `account != "expenses:groceries"`, expected: []string{"TX1", "TX3"}},
+			{filter: `amount > 40`, expected: []string{"TX2", "TX3"}},
+			{filter: `amount = 42.10`, expected: []string{"TX2"}},
+			{filter: `memo contains "work" and account ~ "expenses:*"`, expected: []string{"TX1"}},
+			{filter: `date in 2024-Q2 and not description = "transfer"`, expected: []string{"TX1", "TX2"}},
+			{filter: `date > 2024-05-03`, expected: []string{"TX3"}},
+			{filter: `reconcile = "n" and (description ~ "pizza*" or num = "x")`, expected: []string{"TX1"}},
+			{filter: `date < 2024`, expected: []string{}},
+		} {
+			out, err := executeCommand(listTransactionCmd(c), "--filter", tc.filter, "--output=json")
+			if err != nil {
+				t.Fatalf("%s: %v", tc.filter, err)
+			}
+
+			var transactions []*store.Transaction
+			if err := json.Unmarshal([]byte(out), &transactions); err != nil {
+				t.Fatal(err)
+			}
+			guids := []string{}
+			for _, transaction := range transactions {
+				guids = append(guids, transaction.GUID)
+			}
+			if strings.Join(guids, ",") != strings.Join(tc.expected, ",") {
+				t.Fatalf("%s: expected %v but got %v", tc.filter, tc.expected, guids)
+			}
+		}
+
+		for _, expr := range []string{`amount > fifty`, `foo = 1`, `account = "expenses:nope"`, `date in 2024-Q5`, `(memo = "x"`} {
+			if _, err := executeCommand(listTransactionCmd(c), "--filter", expr); !errors.Is(err, filter.ErrInvalidFilter) {
+				t.Fatalf("%s: expected invalid filter but got %v", expr, err)
+			}
+		}
+	})
+}
+
+func TestBulkUpdateTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "DININGGUID", "Dining", "EXPENSE", "EXPENSESGUID")
+	insertTestingAccount(ctx, db, t, "PIZZAGUID", "Pizza", "EXPENSE", "DININGGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "POS 1234 Pizza Hut", "PIZZAGUID", "BANKGUID", 2500)
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-03", "POS 5678 Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX3", "2024-05-04", "Transfer", "BANKGUID", "ROOTGUID", 10000)
+
+	t.Run("dry run", func(t *testing.T) {
+		c := &cli{db: db, dryRun: true}
+		out, err := executeCommand(bulkUpdateTransactionCmd(c), "--description-regex", `^POS \d+ (.*)$`, "--set-description", "$1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "would update 2 transactions and 0 splits") {
+			t.Fatalf("expected count of transactions that would be updated but got %s", out)
+		}
+
+		assertTransactionDescription(ctx, db, t, "TX1", "POS 1234 Pizza Hut")
+	})
+
+	c := &cli{db: db}
+
+	t.Run("description", func(t *testing.T) {
+		out, err := executeCommand(bulkUpdateTransactionCmd(c), "--description-regex", `^POS \d+ (.*)$`, "--set-description", "$1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "updated 2 transactions and 0 splits") {
+			t.Fatalf("expected count of updated transactions but got %s", out)
+		}
+
+		assertTransactionDescription(ctx, db, t, "TX1", "Pizza Hut")
+		assertTransactionDescription(ctx, db, t, "TX2", "Woolworths")
+		assertTransactionDescription(ctx, db, t, "TX3", "Transfer")
+	})
+
+	t.Run("split fields of account subtree", func(t *testing.T) {
+		out, err := executeCommand(bulkUpdateTransactionCmd(c), "--account=expenses", "--include-children", "--max-amount=30", "--set-reconcile-state=c", "--set-memo=dinner")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out, "updated 1 transactions and 1 splits") {
+			t.Fatalf("expected count of updated splits but got %s", out)
+		}
+
+		for guid, expected := range map[string]string{"TX1-0": "c dinner", "TX1-1": "n ", "TX2-0": "n "} {
+			var state, memo string
+			if err := db.QueryRowContext(ctx, "SELECT reconcile_state, memo FROM splits WHERE guid = ?", guid).Scan(&state, &memo); err != nil {
+				t.Fatal(err)
+			}
+			if state+" "+memo != expected {
+				t.Fatalf("expected split %s to be %q but got %q", guid, expected, state+" "+memo)
+			}
+		}
+	})
+
+	t.Run("reconcile date", func(t *testing.T) {
+		reconcileDate := func() sql.NullString {
+			t.Helper()
+			var date sql.NullString
+			if err := db.QueryRowContext(ctx, "SELECT reconcile_date FROM splits WHERE guid = 'TX1-0'").Scan(&date); err != nil {
+				t.Fatal(err)
+			}
+			return date
+		}
+
+		if _, err := executeCommand(bulkUpdateTransactionCmd(c), "--account=expenses", "--include-children", "--max-amount=30", "--set-reconcile-state=y"); err != nil {
+			t.Fatal(err)
+		}
+		if !reconcileDate().Valid {
+			t.Fatal("expected a reconcile date once reconciled")
+		}
+
+		if _, err := executeCommand(bulkUpdateTransactionCmd(c), "--account=expenses", "--include-children", "--max-amount=30", "--set-reconcile-state=c"); err != nil {
+			t.Fatal(err)
+		}
+		if date := reconcileDate(); date.Valid {
+			t.Fatalf("expected the reconcile date to be cleared but got %s", date.String)
+		}
+	})
+
+	t.Run("post date and num", func(t *testing.T) {
+		if _, err := executeCommand(bulkUpdateTransactionCmd(c), "--filter", "date in 2024-05 and amount >= 42.10", "--shift-post-date=-1", "--set-num=42"); err != nil {
+			t.Fatal(err)
+		}
+
+		for guid, expected := range map[string]string{"TX1": "2024-05-02 10:59:00 ", "TX2": "2024-05-02 10:59:00 42", "TX3": "2024-05-03 10:59:00 42"} {
+			var postDate, num string
+			if err := db.QueryRowContext(ctx, "SELECT post_date, num FROM transactions WHERE guid = ?", guid).Scan(&postDate, &num); err != nil {
+				t.Fatal(err)
+			}
+			if postDate+" "+num != expected {
+				t.Fatalf("expected transaction %s to be %q but got %q", guid, expected, postDate+" "+num)
+			}
+		}
+	})
+
+	if _, err := executeCommand(bulkUpdateTransactionCmd(c), "--set-reconcile-state=x"); err == nil {
+		t.Fatal("expected invalid reconcile state error")
+	}
+}
+
+func assertTransactionDescription(ctx context.Context, db *sql.DB, t *testing.T, guid, expected string) {
+	t.Helper()
+
+	var description string
+	if err := db.QueryRowContext(ctx, "SELECT description FROM transactions WHERE guid = ?", guid).Scan(&description); err != nil {
+		t.Fatal(err)
+	}
+	if description != expected {
+		t.Fatalf("expected description of %s to be %q but got %q", guid, expected, description)
+	}
+}
+
+func TestUpdateTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingAccount(ctx, db, t, "HOUSEHOLDGUID", "Household", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+
+	c := &cli{db: db}
+
+	t.Run("fields and amount", func(t *testing.T) {
+		_, err := executeCommand(updateTransactionCmd(c), "TX1",
+			"--description", "Coles",
+			"--post-date", "2024-05-03",
+			"--num", "42",
+			"--memo", "expenses:groceries=milk",
+			"--memo", "TX1-1=card",
+			"--amount", "50",
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var description, postDate, num string
+		if err := db.QueryRowContext(ctx, "SELECT description, post_date, num FROM transactions WHERE guid = 'TX1'").Scan(&description, &postDate, &num); err != nil {
+			t.Fatal(err)
+		}
+		if description != "Coles" || postDate != "2024-05-03 10:59:00" || num != "42" {
+			t.Fatalf("expected Coles posted 2024-05-03 10:59:00 with num 42 but got %s %s %s", description, postDate, num)
+		}
+
+		for guid, expected := range map[string]string{"TX1-0": "milk 5000 5000", "TX1-1": "card -5000 -5000"} {
+			var memo string
+			var value, quantity int64
+			if err := db.QueryRowContext(ctx, "SELECT memo, value_num, quantity_num FROM splits WHERE guid = ?", guid).Scan(&memo, &value, &quantity); err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%s %d %d", memo, value, quantity); got != expected {
+				t.Fatalf("expected split %s to be %q but got %q", guid, expected, got)
+			}
+		}
+	})
+
+	t.Run("unbalanced", func(t *testing.T) {
+		_, err := executeCommand(updateTransactionCmd(c), "TX1", "--remove-split", "expenses:groceries", "--add-split", "expenses:groceries=30", "--description", "Unbalanced")
+		if !errors.Is(err, ErrTransactionUnbalanced) {
+			t.Fatalf("expected ErrTransactionUnbalanced but got %v", err)
+		}
+
+		var count int
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM splits WHERE tx_guid = 'TX1' AND guid = 'TX1-0'").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Fatal("expected the removed split to be rolled back")
+		}
+		assertTransactionDescription(ctx, db, t, "TX1", "Coles")
+	})
+
+	t.Run("add and remove splits", func(t *testing.T) {
+		_, err := executeCommand(updateTransactionCmd(c), "TX1", "--remove-split", "TX1-0", "--add-split", "expenses:groceries=30", "--add-split", "expenses:household=20")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rows, err := db.QueryContext(ctx, "SELECT account_guid, value_num FROM splits WHERE tx_guid = 'TX1' ORDER BY value_num")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var got []string
+		for rows.Next() {
+			var account string
+			var value int64
+			if err := rows.Scan(&account, &value); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, fmt.Sprintf("%s=%d", account, value))
+		}
+		if expected := "BANKGUID=-5000,HOUSEHOLDGUID=2000,GROCERIESGUID=3000"; strings.Join(got, ",") != expected {
+			t.Fatalf("expected splits %s but got %s", expected, strings.Join(got, ","))
+		}
+
+		if _, err := executeCommand(updateTransactionCmd(c), "TX1", "--amount", "10"); err == nil {
+			t.Fatal("expected --amount to be refused on a transaction with three splits")
+		}
+	})
+
+	t.Run("reconciled", func(t *testing.T) {
+		insertTestingTransaction(ctx, db, t, "TX2", "2024-05-04", "Aldi", "GROCERIESGUID", "BANKGUID", 1000)
+		if _, err := db.ExecContext(ctx, "UPDATE splits SET reconcile_state = 'y' WHERE guid = 'TX2-1'"); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := executeCommand(updateTransactionCmd(c), "TX2", "--amount", "12")
+		if !errors.Is(err, ErrSplitReconciled) {
+			t.Fatalf("expected ErrSplitReconciled but got %v", err)
+		}
+
+		if _, err := executeCommand(updateTransactionCmd(&cli{db: db, force: true}), "TX2", "--amount", "12"); err != nil {
+			t.Fatal(err)
+		}
+		var value int64
+		if err := db.QueryRowContext(ctx, "SELECT value_num FROM splits WHERE guid = 'TX2-1'").Scan(&value); err != nil {
+			t.Fatal(err)
+		}
+		if value != -1200 {
+			t.Fatalf("expected the reconciled split to be -1200 with --force but got %d", value)
+		}
+	})
+
+	t.Run("thousands separator", func(t *testing.T) {
+		for amount, expected := range map[string]int64{"1,234.56": 123456, "12,5": 1250} {
+			if _, err := executeCommand(updateTransactionCmd(&cli{db: db, force: true}), "TX2", "--amount", amount); err != nil {
+				t.Fatal(err)
+			}
+			var value int64
+			if err := db.QueryRowContext(ctx, "SELECT value_num FROM splits WHERE guid = 'TX2-0'").Scan(&value); err != nil {
+				t.Fatal(err)
+			}
+			if value != expected {
+				t.Fatalf("expected amount %s to be %d but got %d", amount, expected, value)
+			}
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gt/internal/render"
+	"gt/internal/store"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Slots gnucash keeps on a voided transaction and its splits.
+const (
+	notesSlot            = "notes"
+	readOnlySlot         = "trans-read-only"
+	voidReasonSlot       = "void-reason"
+	voidTimeSlot         = "void-time"
+	voidFormerNotesSlot  = "void-former-notes"
+	voidFormerAmountSlot = "void-former-amount"
+	voidFormerValueSlot  = "void-former-value"
+)
+
+// gnucash stores these translated, the untranslated strings are what it writes
+// with an English locale.
+const (
+	voidNotes    = "Voided transaction"
+	voidReadOnly = "Transaction Voided"
+)
+
+var (
+	ErrTransactionVoided    = errors.New("transaction is voided")
+	ErrTransactionNotVoided = errors.New("transaction is not voided")
+)
+
+func voidTransactionCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		reason    string
+		output    string
+		shortName bool
+	}
+	var cmd = &cobra.Command{
+		Use:         "void [guid]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Void a transaction",
+		Long: `Void a transaction.
+
+The transaction is voided as gnucash does it: the values and quantities
+of its splits are zeroed and their reconcile state set to v, while the
+former amounts, the reason and the time of voiding are kept in slots so
+the transaction can be unvoided. gnucash shows the transaction as voided
+and read-only.`,
+		Example: `  gt transaction void <guid> --reason "entered twice"`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			guid := args[0]
+			if flags.reason == "" {
+				return fmt.Errorf("--reason is required")
+			}
+
+			var transaction *store.Transaction
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				var err error
+				transaction, err = getTransaction(cmd.Context(), txStore, guid)
+				if err != nil {
+					return err
+				}
+				now := time.Now().UTC().Truncate(time.Second)
+				return voidTransaction(cmd.Context(), txStore, transaction, flags.reason, now)
+			})
+			if err != nil || !committed {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			renderOpts := []render.RendererOptsFunc{render.WithAccountShortName(flags.shortName)}
+			return r.Render(cmd.OutOrStdout(), transaction, renderOpts...)
+		},
+	}
+	cmd.Flags().StringVar(&flags.reason, "reason", "", "Why the transaction is voided")
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
+	cmd.MarkFlagRequired("reason")
+	return cmd
+}
+
+func unvoidTransactionCmd(cli *cli) *cobra.Command {
+	var flags struct {
+		output    string
+		shortName bool
+	}
+	var cmd = &cobra.Command{
+		Use:         "unvoid [guid]",
+		Annotations: map[string]string{annotationWrite: "true"},
+		Short:       "Unvoid a voided transaction",
+		Long: `Unvoid a voided transaction.
+
+The values and quantities the splits had before the transaction was
+voided are restored, their reconcile state set to n and the void slots
+removed, as gnucash does when a transaction is unvoided.`,
+		Example: `  gt transaction unvoid <guid>`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			guid := args[0]
+
+			var transaction *store.Transaction
+			committed, err := cli.write(cmd, flags.output, func(txStore *store.Store) error {
+				var err error
+				transaction, err = getTransaction(cmd.Context(), txStore, guid)
+				if err != nil {
+					return err
+				}
+				return unvoidTransaction(cmd.Context(), txStore, transaction)
+			})
+			if err != nil || !committed {
+				return err
+			}
+
+			r, err := render.New(flags.output)
+			if err != nil {
+				return err
+			}
+
+			renderOpts := []render.RendererOptsFunc{render.WithAccountShortName(flags.shortName)}
+			return r.Render(cmd.OutOrStdout(), transaction, renderOpts...)
+		},
+	}
+	cmd.Flags().StringVar(&flags.output, "output", "table", FlagsUsageOutput)
+	cmd.Flags().BoolVar(&flags.shortName, "short-name", false, FlagsUsageAccountShortName)
+	return cmd
+}
+
+// voidTransaction voids transaction the way xaccTransVoid does.
+func voidTransaction(ctx context.Context, s *store.Store, transaction *store.Transaction, reason string, now time.Time) error {
+	voided, err := s.Slots.Get(ctx, transaction.GUID, voidReasonSlot)
+	if err != nil {
+		return err
+	}
+	if voided != nil {
+		return fmt.Errorf("%w: %s", ErrTransactionVoided, transaction.GUID)
+	}
+
+	notes, err := s.Slots.Get(ctx, transaction.GUID, notesSlot)
+	if err != nil {
+		return err
+	}
+	if notes != nil && notes.StringVal != nil {
+		if err := s.Slots.Set(ctx, store.NewStringSlot(transaction.GUID, voidFormerNotesSlot, *notes.StringVal)); err != nil {
+			return err
+		}
+	}
+
+	for _, slot := range [][2]string{
+		{notesSlot, voidNotes},
+		{voidReasonSlot, reason},
+		{voidTimeSlot, now.UTC().Format("2006-01-02 15:04:05 -0700")},
+		{readOnlySlot, voidReadOnly},
+	} {
+		if err := s.Slots.Set(ctx, store.NewStringSlot(transaction.GUID, slot[0], slot[1])); err != nil {
+			return err
+		}
+	}
+
+	for _, split := range transaction.Splits {
+		if err := s.Slots.Set(ctx, store.NewNumericSlot(split.GUID, voidFormerAmountSlot, split.QuantityNum, split.QuantityDenom)); err != nil {
+			return err
+		}
+		if err := s.Slots.Set(ctx, store.NewNumericSlot(split.GUID, voidFormerValueSlot, split.ValueNum, split.ValueDenom)); err != nil {
+			return err
+		}
+
+		split.ValueNum, split.QuantityNum = 0, 0
+		split.ReconcileState = "v"
+		if err := s.Splits.Update(ctx, split); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unvoidTransaction restores a voided transaction the way xaccTransUnvoid
+// does.
+func unvoidTransaction(ctx context.Context, s *store.Store, transaction *store.Transaction) error {
+	voided, err := s.Slots.Get(ctx, transaction.GUID, voidReasonSlot)
+	if err != nil {
+		return err
+	}
+	if voided == nil {
+		return fmt.Errorf("%w: %s", ErrTransactionNotVoided, transaction.GUID)
+	}
+
+	notes, err := s.Slots.Get(ctx, transaction.GUID, voidFormerNotesSlot)
+	if err != nil {
+		return err
+	}
+	if notes != nil && notes.StringVal != nil {
+		if err := s.Slots.Set(ctx, store.NewStringSlot(transaction.GUID, notesSlot, *notes.StringVal)); err != nil {
+			return err
+		}
+	} else if err := s.Slots.DeleteNamed(ctx, transaction.GUID, notesSlot); err != nil {
+		return err
+	}
+	if err := s.Slots.DeleteNamed(ctx, transaction.GUID, voidFormerNotesSlot, voidReasonSlot, voidTimeSlot, readOnlySlot); err != nil {
+		return err
+	}
+
+	for _, split := range transaction.Splits {
+		amount, err := s.Slots.Get(ctx, split.GUID, voidFormerAmountSlot)
+		if err != nil {
+			return err
+		}
+		value, err := s.Slots.Get(ctx, split.GUID, voidFormerValueSlot)
+		if err != nil {
+			return err
+		}
+		if amount == nil || value == nil || amount.NumericValNum == nil || value.NumericValNum == nil {
+			return fmt.Errorf("%w: split %s has no former amount", ErrTransactionNotVoided, split.GUID)
+		}
+
+		split.QuantityNum, split.QuantityDenom = *amount.NumericValNum, *amount.NumericValDenom
+		split.ValueNum, split.ValueDenom = *value.NumericValNum, *value.NumericValDenom
+		split.ReconcileState = "n"
+		if err := s.Splits.Update(ctx, split); err != nil {
+			return err
+		}
+		if err := s.Slots.DeleteNamed(ctx, split.GUID, voidFormerAmountSlot, voidFormerValueSlot); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVoidTransactionCmd(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestingDB(ctx, t)
+	defer cleanup()
+
+	insertTestingAccount(ctx, db, t, "BANKGUID", "Bank", "BANK", "ROOTGUID")
+	insertTestingAccount(ctx, db, t, "GROCERIESGUID", "Groceries", "EXPENSE", "EXPENSESGUID")
+	insertTestingTransaction(ctx, db, t, "TX1", "2024-05-02", "Woolworths", "GROCERIESGUID", "BANKGUID", 4210)
+	insertTestingTransaction(ctx, db, t, "TX2", "2024-05-03", "Coles", "GROCERIESGUID", "BANKGUID", 1001)
+
+	splits := func(guid string) string {
+		t.Helper()
+
+		rows, err := db.QueryContext(ctx, "SELECT guid, reconcile_state, value_num, quantity_num FROM splits WHERE tx_guid = ? ORDER BY guid", guid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var got []string
+		for rows.Next() {
+			var split, state string
+			var value, quantity int64
+			if err := rows.Scan(&split, &state, &value, &quantity); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, fmt.Sprintf("%s %s %d %d", split, state, value, quantity))
+		}
+		return strings.Join(got, "\n")
+	}
+
+	slots := func(guid string) string {
+		t.Helper()
+
+		rows, err := db.QueryContext(ctx, `
+SELECT obj_guid, name, COALESCE(string_val, ''), COALESCE(numeric_val_num, 0), COALESCE(numeric_val_denom, 0)
+FROM slots
+WHERE obj_guid = ? OR obj_guid IN (SELECT guid FROM splits WHERE tx_guid = ?)
+ORDER BY obj_guid, name`, guid, guid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var got []string
+		for rows.Next() {
+			var obj, name, value string
+			var num, denom int64
+			if err := rows.Scan(&obj, &name, &value, &num, &denom); err != nil {
+				t.Fatal(err)
+			}
+			if name == voidTimeSlot {
+				if _, err := time.Parse("2006-01-02 15:04:05 -0700", value); err != nil {
+					t.Fatalf("expected void-time with a timezone offset but got %s", value)
+				}
+				value = "<time>"
+			}
+			if denom != 0 {
+				value = fmt.Sprintf("%d/%d", num, denom)
+			}
+			got = append(got, fmt.Sprintf("%s %s %s", obj, name, value))
+		}
+		return strings.Join(got, "\n")
+	}
+
+	c := &cli{db: db}
+
+	t.Run("void", func(t *testing.T) {
+		if _, err := executeCommand(voidTransactionCmd(c), "TX1", "--reason", "entered twice"); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `TX1-0 v 0 0
+TX1-1 v 0 0`
+		if got := splits("TX1"); got != expected {
+			t.Fatalf("expected splits\n%s\nbut got\n%s", expected, got)
+		}
+
+		expected = `TX1 notes Voided transaction
+TX1 trans-read-only Transaction Voided
+TX1 void-reason entered twice
+TX1 void-time <time>
+TX1-0 void-former-amount 4210/100
+TX1-0 void-former-value 4210/100
+TX1-1 void-former-amount -4210/100
+TX1-1 void-former-value -4210/100`
+		if got := slots("TX1"); got != expected {
+			t.Fatalf("expected slots\n%s\nbut got\n%s", expected, got)
+		}
+	})
+
+	t.Run("void voided", func(t *testing.T) {
+		_, err := executeCommand(voidTransactionCmd(c), "TX1", "--reason", "again")
+		if !errors.Is(err, ErrTransactionVoided) {
+			t.Fatalf("expected ErrTransactionVoided but got %v", err)
+		}
+	})
+
+	t.Run("list voided", func(t *testing.T) {
+		for voided, expected := range map[string]string{
+			"include": "TX1,TX2",
+			"exclude": "TX2",
+			"only":    "TX1",
+		} {
+			out, err := executeCommand(listTransactionCmd(c), "--voided", voided, "--output", "json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, guid := range []string{"TX1", "TX2"} {
+				if strings.Contains(out, `"`+guid+`"`) {
+					got = append(got, guid)
+				}
+			}
+			if strings.Join(got, ",") != expected {
+				t.Fatalf("expected %s with --voided %s but got %v", expected, voided, got)
+			}
+		}
+	})
+
+	t.Run("unvoid", func(t *testing.T) {
+		if _, err := executeCommand(unvoidTransactionCmd(c), "TX1"); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := `TX1-0 n 4210 4210
+TX1-1 n -4210 -4210`
+		if got := splits("TX1"); got != expected {
+			t.Fatalf("expected splits\n%s\nbut got\n%s", expected, got)
+		}
+		if got := slots("TX1"); got != "" {
+			t.Fatalf("expected no slots but got\n%s", got)
+		}
+	})
+
+	t.Run("unvoid not voided", func(t *testing.T) {
+		_, err := executeCommand(unvoidTransactionCmd(c), "TX2")
+		if !errors.Is(err, ErrTransactionNotVoided) {
+			t.Fatalf("expected ErrTransactionNotVoided but got %v", err)
+		}
+	})
+}
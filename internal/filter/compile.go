@@ -0,0 +1,208 @@
+package filter
+
+import (
+	"fmt"
+	"gt/internal/store"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Compile compiles expr into a WHERE clause on the transactions table and its
+// arguments. Account names are resolved against tree.
+//
+// Text comparisons ignore case and ~ matches glob patterns (e.g.
+// "expenses:dining*"). Conditions on account, memo, action and reconcile hold
+// when any split of the transaction satisfies them, so != and not hold when
+// no split does.
+func Compile(expr Expr, tree *store.AccountTree) (string, []any, error) {
+	c := &compiler{tree: tree}
+	clause, err := c.compile(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	return clause, c.args, nil
+}
+
+// Apply parses the filter s and adds it to q.
+func Apply(q *store.TransactionQuery, s string, tree *store.AccountTree) error {
+	expr, err := Parse(s)
+	if err != nil {
+		return err
+	}
+
+	clause, args, err := Compile(expr, tree)
+	if err != nil {
+		return err
+	}
+
+	q.Where(clause, args...)
+	return nil
+}
+
+type compiler struct {
+	tree *store.AccountTree
+	args []any
+}
+
+func (c *compiler) compile(expr Expr) (string, error) {
+	switch e := expr.(type) {
+	case And:
+		return c.binary("AND", e.Left, e.Right)
+	case Or:
+		return c.binary("OR", e.Left, e.Right)
+	case Not:
+		clause, err := c.compile(e.Expr)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT (%s)", clause), nil
+	case Comparison:
+		return c.comparison(e)
+	default:
+		return "", fmt.Errorf("%w: unsupported expression %T", ErrInvalidFilter, expr)
+	}
+}
+
+func (c *compiler) binary(op string, left, right Expr) (string, error) {
+	l, err := c.compile(left)
+	if err != nil {
+		return "", err
+	}
+	r, err := c.compile(right)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("(%s %s %s)", l, op, r), nil
+}
+
+func (c *compiler) comparison(e Comparison) (string, error) {
+	if e.Op == OpNe {
+		e.Op = OpEq
+		clause, err := c.comparison(e)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT (%s)", clause), nil
+	}
+
+	if err := validate(e); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidFilter, err)
+	}
+
+	switch e.Field {
+	case FieldDescription:
+		return c.text("COALESCE(transactions.description, '')", e), nil
+	case FieldNum:
+		return c.text("transactions.num", e), nil
+	case FieldMemo:
+		return split(c.text("splits.memo", e)), nil
+	case FieldAction:
+		return split(c.text("splits.action", e)), nil
+	case FieldReconcile:
+		c.args = append(c.args, e.Value)
+		return split("splits.reconcile_state = ?"), nil
+	case FieldAccount:
+		return c.account(e)
+	case FieldAmount:
+		amount, _ := strconv.ParseFloat(e.Value, 64)
+		c.args = append(c.args, amount)
+		return fmt.Sprintf(`(
+	SELECT ROUND(SUM(CAST(value_num AS REAL) / value_denom), 6)
+	FROM splits
+	WHERE tx_guid = transactions.guid AND value_num > 0
+) %s ROUND(?, 6)`, e.Op), nil
+	case FieldDate:
+		return c.date(e), nil
+	default:
+		return "", fmt.Errorf("%w: unknown field %s", ErrInvalidFilter, e.Field)
+	}
+}
+
+// split returns a clause that holds when any split of the transaction
+// satisfies cond.
+func split(cond string) string {
+	return fmt.Sprintf("transactions.guid IN (SELECT tx_guid FROM splits WHERE %s)", cond)
+}
+
+func (c *compiler) text(column string, e Comparison) string {
+	c.args = append(c.args, e.Value)
+	switch e.Op {
+	case OpMatch:
+		return fmt.Sprintf("lower(%s) GLOB lower(?)", column)
+	case OpContains:
+		return fmt.Sprintf("instr(lower(%s), lower(?)) > 0", column)
+	default:
+		return fmt.Sprintf("lower(%s) = lower(?)", column)
+	}
+}
+
+// account resolves the accounts e refers to, by guid or full name, or by
+// matching full names when e is a ~ comparison.
+func (c *compiler) account(e Comparison) (string, error) {
+	if c.tree == nil {
+		return "", fmt.Errorf("%w: accounts are not available", ErrInvalidFilter)
+	}
+
+	var guids []string
+	if e.Op == OpMatch {
+		pattern := strings.ToLower(e.Value)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return "", fmt.Errorf("%w: invalid pattern %q", ErrInvalidFilter, e.Value)
+		}
+		var accounts []*store.Account
+		if root := c.tree.Root(); root != nil {
+			accounts = c.tree.Descendants(root.GUID)
+		}
+		for _, account := range accounts {
+			if ok, _ := path.Match(pattern, strings.ToLower(account.FullName)); ok {
+				guids = append(guids, account.GUID)
+			}
+		}
+	} else {
+		account, ok := c.tree.Get(e.Value)
+		if !ok {
+			var err error
+			if account, err = c.tree.Lookup(e.Value); err != nil {
+				return "", fmt.Errorf("%w: account %s does not exist", ErrInvalidFilter, e.Value)
+			}
+		}
+		guids = append(guids, account.GUID)
+	}
+
+	if len(guids) == 0 {
+		return "0", nil
+	}
+
+	placeholders := make([]string, len(guids))
+	for i, guid := range guids {
+		placeholders[i] = "?"
+		c.args = append(c.args, guid)
+	}
+	return split(fmt.Sprintf("splits.account_guid IN (%s)", strings.Join(placeholders, ","))), nil
+}
+
+// date compares the post date to the period e refers to, so date <= 2024-05
+// holds up to the end of May and date > 2024-05 from June.
+func (c *compiler) date(e Comparison) string {
+	start, end, _ := ParsePeriod(e.Value)
+	startArg, endArg := start.Format("2006-01-02 15:04:05"), end.Format("2006-01-02 15:04:05")
+
+	switch e.Op {
+	case OpLt:
+		c.args = append(c.args, startArg)
+		return "transactions.post_date < ?"
+	case OpLe:
+		c.args = append(c.args, endArg)
+		return "transactions.post_date < ?"
+	case OpGt:
+		c.args = append(c.args, endArg)
+		return "transactions.post_date >= ?"
+	case OpGe:
+		c.args = append(c.args, startArg)
+		return "transactions.post_date >= ?"
+	default:
+		c.args = append(c.args, startArg, endArg)
+		return "(transactions.post_date >= ? AND transactions.post_date < ?)"
+	}
+}
@@ -0,0 +1,349 @@
+// Package filter parses transaction filter expressions such as
+//
+//	account ~ "expenses:dining*" and amount > 50 and date in 2024-Q2
+//
+// into an AST that is compiled to a WHERE clause with bound parameters.
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Field is a property of a transaction or of its splits that a filter
+// compares.
+type Field string
+
+const (
+	// FieldAccount is the full name or guid of the account of any split.
+	FieldAccount Field = "account"
+	// FieldAmount is the transaction amount, the sum of its debit splits.
+	FieldAmount      Field = "amount"
+	FieldDate        Field = "date"
+	FieldDescription Field = "description"
+	FieldNum         Field = "num"
+	FieldMemo        Field = "memo"
+	FieldAction      Field = "action"
+	FieldReconcile   Field = "reconcile"
+)
+
+type Op string
+
+const (
+	OpEq       Op = "="
+	OpNe       Op = "!="
+	OpLt       Op = "<"
+	OpLe       Op = "<="
+	OpGt       Op = ">"
+	OpGe       Op = ">="
+	OpMatch    Op = "~"
+	OpContains Op = "contains"
+	OpIn       Op = "in"
+)
+
+// fieldOps are the operators each field supports.
+var fieldOps = map[Field][]Op{
+	FieldAccount:     {OpEq, OpNe, OpMatch},
+	FieldAmount:      {OpEq, OpNe, OpLt, OpLe, OpGt, OpGe},
+	FieldDate:        {OpEq, OpNe, OpLt, OpLe, OpGt, OpGe, OpIn},
+	FieldDescription: {OpEq, OpNe, OpMatch, OpContains},
+	FieldNum:         {OpEq, OpNe, OpMatch, OpContains},
+	FieldMemo:        {OpEq, OpNe, OpMatch, OpContains},
+	FieldAction:      {OpEq, OpNe, OpMatch, OpContains},
+	FieldReconcile:   {OpEq, OpNe},
+}
+
+// Expr is a node of a parsed filter.
+type Expr interface {
+	expr()
+}
+
+type And struct {
+	Left, Right Expr
+}
+
+type Or struct {
+	Left, Right Expr
+}
+
+type Not struct {
+	Expr Expr
+}
+
+// Comparison compares a field to a value, e.g. amount > 50.
+type Comparison struct {
+	Field Field
+	Op    Op
+	Value string
+}
+
+func (And) expr()        {}
+func (Or) expr()         {}
+func (Not) expr()        {}
+func (Comparison) expr() {}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// is reports whether t is the bare word keyword, regardless of case.
+func (t token) is(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case r == '=' || r == '~':
+			tokens = append(tokens, token{kind: tokenOp, text: string(r), pos: i})
+			i++
+		case r == '!' || r == '<' || r == '>':
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, token{kind: tokenOp, text: string(runes[i : i+2]), pos: i})
+				i += 2
+				continue
+			}
+			if r == '!' {
+				return nil, fmt.Errorf("%w: unexpected ! at position %d", ErrInvalidFilter, i+1)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: string(r), pos: i})
+			i++
+		case r == '"':
+			var b strings.Builder
+			start := i
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, fmt.Errorf("%w: unterminated string at position %d", ErrInvalidFilter, start+1)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: b.String(), pos: start})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"=~!<>`, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: string(runes[start:i]), pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a filter expression. Comparisons are joined with and, or and
+// not, and grouped with parentheses; and binds tighter than or.
+func Parse(s string) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d", ErrInvalidFilter, fmt.Sprintf(format, args...), t.pos+1)
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.peek().is("not") {
+		p.next()
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenLParen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected ) but got %s", closing)
+		}
+		return expr, nil
+	case t.kind != tokenWord:
+		return nil, p.errorf(t, "expected a field but got %s", t)
+	}
+
+	field := Field(strings.ToLower(t.text))
+	ops, ok := fieldOps[field]
+	if !ok {
+		return nil, p.errorf(t, "unknown field %s", t)
+	}
+
+	opToken := p.next()
+	op := Op(strings.ToLower(opToken.text))
+	if opToken.kind != tokenOp && !opToken.is(string(OpContains)) && !opToken.is(string(OpIn)) || !slices.Contains(ops, op) {
+		return nil, p.errorf(opToken, "expected an operator for %s but got %s", field, opToken)
+	}
+
+	valueToken := p.next()
+	if valueToken.kind != tokenWord && valueToken.kind != tokenString {
+		return nil, p.errorf(valueToken, "expected a value but got %s", valueToken)
+	}
+
+	c := Comparison{Field: field, Op: op, Value: valueToken.text}
+	if err := validate(c); err != nil {
+		return nil, p.errorf(valueToken, "%s", err)
+	}
+	return c, nil
+}
+
+// validate checks the value of c can be compared to its field.
+func validate(c Comparison) error {
+	switch c.Field {
+	case FieldAmount:
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			return fmt.Errorf("invalid amount %q", c.Value)
+		}
+	case FieldDate:
+		if _, _, err := ParsePeriod(c.Value); err != nil {
+			return err
+		}
+	case FieldReconcile:
+		if len(c.Value) != 1 || !strings.Contains("ncyfv", c.Value) {
+			return fmt.Errorf("invalid reconcile state %q, expected one of n, c, y, f or v", c.Value)
+		}
+	}
+	return nil
+}
+
+// ParsePeriod parses a day (2024-05-02), month (2024-05), quarter (2024-Q2)
+// or year (2024) and returns its first day and the day after its last, in
+// UTC.
+func ParsePeriod(s string) (start, end time.Time, err error) {
+	if year, quarter, ok := strings.Cut(strings.ToUpper(s), "-Q"); ok {
+		y, yErr := strconv.Atoi(year)
+		q, qErr := strconv.Atoi(quarter)
+		if yErr != nil || qErr != nil || len(year) != 4 || q < 1 || q > 4 {
+			return start, end, fmt.Errorf("invalid date %q", s)
+		}
+		start = time.Date(y, time.Month(3*(q-1)+1), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 3, 0), nil
+	}
+
+	for _, period := range []struct {
+		layout              string
+		years, months, days int
+	}{
+		{layout: "2006-01-02", days: 1},
+		{layout: "2006-01", months: 1},
+		{layout: "2006", years: 1},
+	} {
+		if start, err = time.Parse(period.layout, s); err == nil {
+			return start, start.AddDate(period.years, period.months, period.days), nil
+		}
+	}
+	return start, end, fmt.Errorf("invalid date %q, expected YYYY-MM-DD, YYYY-MM, YYYY-Qn or YYYY", s)
+}
@@ -0,0 +1,127 @@
+// Package journal keeps a record of every change gt makes to a book in a
+// sidecar file so changes can be listed and undone.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gt/internal/store"
+)
+
+var ErrEntryNotFound = errors.New("journal entry not found")
+
+// Entry is one operation performed by gt.
+type Entry struct {
+	ID      int
+	Time    time.Time
+	Command string
+	// Undoes is the id of the entry this entry reverted.
+	Undoes int `json:",omitempty"`
+	// UndoneBy is the id of the entry that reverted this entry. It is not
+	// stored but derived when the journal is read.
+	UndoneBy int `json:",omitempty"`
+	// Restore is the id of the backup the book was restored from. Changes
+	// made before a restore can not be undone.
+	Restore string `json:",omitempty"`
+	Changes []store.Change
+}
+
+// Journal is a file of JSON encoded entries, one per line.
+type Journal struct {
+	path string
+}
+
+func New(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// Path returns the journal file of the book at bookPath.
+func Path(bookPath string) string {
+	return bookPath + ".gt-journal"
+}
+
+// All returns every entry of the journal, oldest first.
+func (j *Journal) All() ([]*Entry, error) {
+	f, err := os.Open(j.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*Entry{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []*Entry{}
+	byID := make(map[int]*Entry)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", j.path, lineNum, err)
+		}
+		entry.UndoneBy = 0
+
+		if undone, ok := byID[entry.Undoes]; ok {
+			undone.UndoneBy = entry.ID
+		}
+
+		entries = append(entries, &entry)
+		byID[entry.ID] = &entry
+	}
+
+	return entries, scanner.Err()
+}
+
+// Get returns the entry with id.
+func (j *Journal) Get(id int) (*Entry, error) {
+	entries, err := j.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return nil, ErrEntryNotFound
+}
+
+// Append assigns entry the next id and appends it to the journal.
+func (j *Journal) Append(entry *Entry) error {
+	entries, err := j.All()
+	if err != nil {
+		return err
+	}
+
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,321 @@
+// Package match matches bank statement lines to transactions already in a
+// gnucash book so that hand entered transactions are not imported twice.
+package match
+
+import (
+	"gt/internal/statement"
+	"gt/internal/store"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type Status string
+
+const (
+	// StatusMatched is a line confidently matched to an existing split.
+	StatusMatched Status = "matched"
+	// StatusAmbiguous is a line with candidate splits none of which is a
+	// confident match. It needs to be resolved by hand.
+	StatusAmbiguous Status = "ambiguous"
+	// StatusUnmatched is a line without candidate splits.
+	StatusUnmatched Status = "unmatched"
+)
+
+// Candidate is an existing split a statement line may match.
+type Candidate struct {
+	Transaction *store.Transaction
+	Split       *store.Split
+	// Days is the number of days between the line's booking date and the
+	// transaction's post date.
+	Days int
+	// Similarity is how similar the descriptions are, from 0 to 1.
+	Similarity float64
+}
+
+// Result is the outcome of matching a statement line.
+type Result struct {
+	Line       statement.Line
+	Status     Status
+	Match      *Candidate
+	Candidates []*Candidate
+}
+
+// Matcher matches statement lines to the splits of an account.
+type Matcher struct {
+	splits []*Candidate
+	used   map[string]bool
+	opts   *MatcherOpts
+}
+
+type MatcherOpts struct {
+	window        int
+	minSimilarity float64
+}
+
+func defaultMatcherOpts() *MatcherOpts {
+	return &MatcherOpts{
+		window:        3,
+		minSimilarity: 0.5,
+	}
+}
+
+type MatcherOptFunc func(*MatcherOpts)
+
+// WithWindow sets the number of days a transaction's post date may be away
+// from the line's booking date.
+func WithWindow(days int) MatcherOptFunc {
+	return func(o *MatcherOpts) {
+		o.window = days
+	}
+}
+
+// WithMinSimilarity sets the description similarity from which a candidate
+// posted on another day than the line is a confident match.
+func WithMinSimilarity(similarity float64) MatcherOptFunc {
+	return func(o *MatcherOpts) {
+		o.minSimilarity = similarity
+	}
+}
+
+// NewMatcher returns a matcher for the splits of the account identified by
+// accountGUID in transactions. Reconciled and voided splits are not matched.
+func NewMatcher(accountGUID string, transactions []*store.Transaction, opts ...MatcherOptFunc) *Matcher {
+	o := defaultMatcherOpts()
+	for _, fn := range opts {
+		fn(o)
+	}
+
+	m := &Matcher{
+		used: make(map[string]bool),
+		opts: o,
+	}
+	for _, transaction := range transactions {
+		if transaction.PostDate == nil {
+			continue
+		}
+		for _, split := range transaction.Splits {
+			if split.AccountGUID != accountGUID || split.ReconcileState == "y" || split.ReconcileState == "v" {
+				continue
+			}
+			m.splits = append(m.splits, &Candidate{Transaction: transaction, Split: split})
+		}
+	}
+	return m
+}
+
+// Match matches line, whose value in the account's commodity is
+// quantityNum/quantityDenom, to the splits of the account. A split is matched
+// at most once.
+//
+// Candidates are splits of the same amount posted within the window of the
+// line's booking date. A candidate is confident when it is posted on the
+// booking date or its description is similar enough. The line matches the
+// only confident candidate or the confident candidate with the single
+// highest similarity, otherwise it is ambiguous.
+func (m *Matcher) Match(line statement.Line, quantityNum, quantityDenom int64) Result {
+	result := Result{Line: line, Status: StatusUnmatched}
+
+	bookingDate := civilDate(line.BookingDate)
+	description := line.Description()
+	for _, split := range m.splits {
+		if m.used[split.Split.GUID] || split.Split.QuantityNum*quantityDenom != quantityNum*split.Split.QuantityDenom {
+			continue
+		}
+
+		days := int(civilDate(*split.Transaction.PostDate).Sub(bookingDate).Hours() / 24)
+		if days < 0 {
+			days = -days
+		}
+		if days > m.opts.window {
+			continue
+		}
+
+		candidate := &Candidate{
+			Transaction: split.Transaction,
+			Split:       split.Split,
+			Days:        days,
+		}
+		if split.Transaction.Description != nil {
+			candidate.Similarity = Similarity(description, *split.Transaction.Description)
+		}
+		result.Candidates = append(result.Candidates, candidate)
+	}
+
+	if len(result.Candidates) == 0 {
+		return result
+	}
+
+	sort.SliceStable(result.Candidates, func(i, j int) bool {
+		a, b := result.Candidates[i], result.Candidates[j]
+		if a.Similarity != b.Similarity {
+			return a.Similarity > b.Similarity
+		}
+		return a.Days < b.Days
+	})
+
+	var confident []*Candidate
+	for _, candidate := range result.Candidates {
+		if candidate.Days == 0 || candidate.Similarity >= m.opts.minSimilarity {
+			confident = append(confident, candidate)
+		}
+	}
+
+	result.Status = StatusAmbiguous
+	switch {
+	case len(confident) == 1:
+		result.Match = confident[0]
+	case len(confident) > 1 && confident[0].Similarity > confident[1].Similarity:
+		result.Match = confident[0]
+	}
+
+	if result.Match != nil {
+		result.Status = StatusMatched
+		m.used[result.Match.Split.GUID] = true
+	}
+
+	return result
+}
+
+// Exclude stops the split identified by guid from being matched, for example
+// because it was imported from another statement line.
+func (m *Matcher) Exclude(guid string) {
+	m.used[guid] = true
+}
+
+// Similarity returns how similar two descriptions are, from 0 to 1, as the
+// share of the words of the shorter description found in the other.
+func Similarity(a, b string) float64 {
+	wordsA, wordsB := words(a), words(b)
+	if len(wordsA) == 0 || len(wordsB) == 0 {
+		return 0
+	}
+	if len(wordsA) > len(wordsB) {
+		wordsA, wordsB = wordsB, wordsA
+	}
+
+	common := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(wordsA))
+}
+
+func words(s string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) > 1 {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// DuplicateGroup is a group of transactions that look like the same
+// transaction entered more than once. The first transaction is the earliest
+// entered and the one to keep.
+type DuplicateGroup struct {
+	Transactions []*store.Transaction
+}
+
+// Duplicates groups transactions with splits on the same accounts of the same
+// amounts posted within window of each other. With minSimilarity above zero
+// their descriptions must also be at least that similar.
+func Duplicates(transactions []*store.Transaction, window time.Duration, minSimilarity float64) []DuplicateGroup {
+	byKey := make(map[string][]*store.Transaction)
+	var keys []string
+	for _, transaction := range transactions {
+		if transaction.PostDate == nil || len(transaction.Splits) == 0 {
+			continue
+		}
+		key := splitsKey(transaction)
+		if _, ok := byKey[key]; !ok {
+			keys = append(keys, key)
+		}
+		byKey[key] = append(byKey[key], transaction)
+	}
+
+	groups := []DuplicateGroup{}
+	for _, key := range keys {
+		candidates := byKey[key]
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].PostDate.Before(*candidates[j].PostDate)
+		})
+
+		used := make(map[string]bool)
+		for i, first := range candidates {
+			if used[first.GUID] {
+				continue
+			}
+
+			group := []*store.Transaction{first}
+			for _, other := range candidates[i+1:] {
+				if other.PostDate.Sub(*first.PostDate) > window {
+					break
+				}
+				if used[other.GUID] || minSimilarity > 0 && Similarity(description(first), description(other)) < minSimilarity {
+					continue
+				}
+				group = append(group, other)
+				used[other.GUID] = true
+			}
+
+			if len(group) > 1 {
+				sort.SliceStable(group, func(i, j int) bool {
+					return enteredBefore(group[i], group[j])
+				})
+				groups = append(groups, DuplicateGroup{Transactions: group})
+			}
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].Transactions[0].PostDate.Before(*groups[j].Transactions[0].PostDate)
+	})
+
+	return groups
+}
+
+// splitsKey identifies the accounts and amounts of a transaction's splits
+// regardless of their order.
+func splitsKey(transaction *store.Transaction) string {
+	parts := make([]string, len(transaction.Splits))
+	for i, split := range transaction.Splits {
+		value := "0"
+		if split.ValueDenom != 0 {
+			value = big.NewRat(split.ValueNum, split.ValueDenom).RatString()
+		}
+		parts[i] = split.AccountGUID + "=" + value
+	}
+	sort.Strings(parts)
+	return transaction.CurrencyGUID + ":" + strings.Join(parts, ",")
+}
+
+func description(transaction *store.Transaction) string {
+	if transaction.Description == nil {
+		return ""
+	}
+	return *transaction.Description
+}
+
+// enteredBefore orders transactions by enter date, those without one last.
+func enteredBefore(a, b *store.Transaction) bool {
+	switch {
+	case a.EnterDate == nil:
+		return false
+	case b.EnterDate == nil:
+		return true
+	default:
+		return a.EnterDate.Before(*b.EnterDate)
+	}
+}
@@ -0,0 +1,140 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"gt/internal/store"
+	"time"
+)
+
+var (
+	ErrSplitNotFound = errors.New("split is not an unreconciled split of the account up to the statement date")
+	ErrNotBalanced   = errors.New("selected splits do not bring the balance to the ending balance")
+)
+
+// Item is an unreconciled split of the account being reconciled.
+type Item struct {
+	Number         int
+	SplitGUID      string
+	TXGUID         string
+	PostDate       *time.Time
+	Description    string
+	ReconcileState string
+	AmountNum      int64
+	Selected       bool
+}
+
+// Reconciliation matches the unreconciled splits of an account against a
+// bank statement. Amounts are in the account's commodity and share Denom.
+type Reconciliation struct {
+	Account         string
+	StatementDate   time.Time
+	StartingBalance int64
+	EndingBalance   int64
+	SelectedBalance int64
+	Difference      int64
+	Denom           int64
+	Items           []*Item
+	Reconciled      bool
+}
+
+// New returns a reconciliation of the account's register entries posted up to
+// the statement date. Reconciled splits make up the starting balance and
+// cleared splits are selected, as gnucash does.
+func New(account string, statementDate time.Time, endingBalance int64, denom int64, entries []*store.RegisterEntry) *Reconciliation {
+	r := &Reconciliation{
+		Account:       account,
+		StatementDate: statementDate,
+		EndingBalance: endingBalance,
+		Denom:         denom,
+		Items:         []*Item{},
+	}
+
+	for _, entry := range entries {
+		if entry.ReconcileState == "y" {
+			r.StartingBalance += entry.AmountNum
+			continue
+		}
+		if entry.ReconcileState == "v" {
+			continue
+		}
+
+		r.Items = append(r.Items, &Item{
+			Number:         len(r.Items) + 1,
+			SplitGUID:      entry.SplitGUID,
+			TXGUID:         entry.TXGUID,
+			PostDate:       entry.PostDate,
+			Description:    entry.Description,
+			ReconcileState: entry.ReconcileState,
+			AmountNum:      entry.AmountNum,
+			Selected:       entry.ReconcileState == "c",
+		})
+	}
+
+	r.update()
+	return r
+}
+
+// Select selects the split identified by guid.
+func (r *Reconciliation) Select(guid string) error {
+	for _, item := range r.Items {
+		if item.SplitGUID == guid {
+			item.Selected = true
+			r.update()
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrSplitNotFound, guid)
+}
+
+// SelectOnly selects the splits identified by guids and deselects every
+// other split.
+func (r *Reconciliation) SelectOnly(guids []string) error {
+	for _, item := range r.Items {
+		item.Selected = false
+	}
+	for _, guid := range guids {
+		if err := r.Select(guid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Toggle flips the selection of the item numbered number.
+func (r *Reconciliation) Toggle(number int) error {
+	if number < 1 || number > len(r.Items) {
+		return fmt.Errorf("no split numbered %d", number)
+	}
+	item := r.Items[number-1]
+	item.Selected = !item.Selected
+	r.update()
+	return nil
+}
+
+// Selected returns the selected items.
+func (r *Reconciliation) Selected() []*Item {
+	var selected []*Item
+	for _, item := range r.Items {
+		if item.Selected {
+			selected = append(selected, item)
+		}
+	}
+	return selected
+}
+
+// Balanced reports whether the selected splits bring the starting balance to
+// the ending balance.
+func (r *Reconciliation) Balanced() bool {
+	return r.Difference == 0
+}
+
+func (r *Reconciliation) update() {
+	r.SelectedBalance = 0
+	for _, item := range r.Items {
+		if item.Selected {
+			r.SelectedBalance += item.AmountNum
+		}
+	}
+	r.Difference = r.EndingBalance - r.StartingBalance - r.SelectedBalance
+}
@@ -3,8 +3,14 @@ package render
 import (
 	"encoding/json"
 	"fmt"
+	"gt/internal/backup"
+	"gt/internal/journal"
+	"gt/internal/match"
+	"gt/internal/reconcile"
+	"gt/internal/rules"
 	"gt/internal/store"
 	"io"
+	"strings"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/olekukonko/tablewriter/tw"
@@ -50,6 +56,10 @@ type RendererOpts struct {
 	// use accounts short name (i.e. pizza) when rendering instead of full name
 	// (i.e expenses:dining:pizza)
 	accountShortName bool
+
+	// totals of the accounts keyed by guid are added to the total of the
+	// account they map to (i.e. expenses:dining:pizza to expenses:dining)
+	totalsRollup map[string]*store.Account
 }
 
 func defaultRendererOpts() *RendererOpts {
@@ -67,6 +77,12 @@ func WithAccountShortName(b bool) RendererOptsFunc {
 	}
 }
 
+func WithTotalsRollup(rollup map[string]*store.Account) RendererOptsFunc {
+	return func(o *RendererOpts) {
+		o.totalsRollup = rollup
+	}
+}
+
 func WithIncludeTotals(b bool) RendererOptsFunc {
 	return func(o *RendererOpts) {
 		o.includeTotals = b
@@ -123,19 +139,23 @@ func renderTransactions(table *tablewriter.Table, opts RendererOpts, transaction
 		})
 
 		for _, split := range transaction.Splits {
+			name := accountName(opts, split.Account, split.AccountGUID)
 			debit, credit := formatAmount(split.ValueNum, split.ValueDenom)
 			table.Append([]string{
 				"",
 				"",
-				split.Account.Name,
+				name,
 				debit,
 				credit,
 			})
 
-			accountGUID := split.AccountGUID
+			accountGUID, totalName := split.AccountGUID, name
+			if account, ok := opts.totalsRollup[split.AccountGUID]; ok {
+				accountGUID, totalName = account.GUID, accountName(opts, account, account.GUID)
+			}
 			if _, exists := accountTotals[accountGUID]; !exists {
 				accountTotals[accountGUID] = &AccountTotal{
-					Name:       split.Account.Name,
+					Name:       totalName,
 					TotalNum:   0,
 					TotalDenom: split.ValueDenom,
 				}
@@ -180,6 +200,237 @@ func renderTransactions(table *tablewriter.Table, opts RendererOpts, transaction
 	}
 }
 
+// accountName returns the name of account, falling back to guid when the
+// account does not exist.
+func accountName(opts RendererOpts, account *store.Account, guid string) string {
+	switch {
+	case account == nil:
+		return guid
+	case opts.accountShortName || account.FullName == "":
+		return account.Name
+	default:
+		return account.FullName
+	}
+}
+
+func renderRegister(table *tablewriter.Table, entries []*store.RegisterEntry) {
+	table.Header([]string{"Date", "Num", "Description", "Transfer", "R", "Amount", "Balance"})
+	for _, entry := range entries {
+		date := ""
+		if entry.PostDate != nil {
+			date = entry.PostDate.Local().Format("2006-01-02")
+		}
+
+		table.Append([]string{
+			date,
+			entry.Num,
+			entry.Description,
+			entry.CounterAccount,
+			entry.ReconcileState,
+			formatDecimal(entry.AmountNum, entry.Denom),
+			formatDecimal(entry.BalanceNum, entry.Denom),
+		})
+	}
+}
+
+func renderReconciliation(table *tablewriter.Table, r *reconcile.Reconciliation) {
+	table.Header([]string{"#", "R", "Date", "Description", "Amount", "Split"})
+	for _, item := range r.Items {
+		selected := item.ReconcileState
+		if item.Selected {
+			selected = "x"
+		}
+
+		date := ""
+		if item.PostDate != nil {
+			date = item.PostDate.Local().Format("2006-01-02")
+		}
+
+		table.Append([]string{
+			fmt.Sprintf("%d", item.Number),
+			selected,
+			date,
+			item.Description,
+			formatDecimal(item.AmountNum, r.Denom),
+			item.SplitGUID,
+		})
+	}
+
+	table.Append([]string{"", "", "", "", "", ""})
+	for _, total := range []struct {
+		name string
+		num  int64
+	}{
+		{name: "STARTING BALANCE", num: r.StartingBalance},
+		{name: "SELECTED", num: r.SelectedBalance},
+		{name: "ENDING BALANCE", num: r.EndingBalance},
+		{name: "DIFFERENCE", num: r.Difference},
+	} {
+		table.Append([]string{"", "", "", total.name, formatDecimal(total.num, r.Denom), ""})
+	}
+}
+
+func renderMatchResults(table *tablewriter.Table, results []match.Result) {
+	table.Header([]string{"Date", "Amount", "Description", "Status", "Transaction"})
+	for _, result := range results {
+		amount := result.Line.Amount
+		if !result.Line.Credit {
+			amount = "-" + amount
+		}
+
+		var guids []string
+		switch {
+		case result.Match != nil:
+			guids = append(guids, result.Match.Transaction.GUID)
+		default:
+			for _, candidate := range result.Candidates {
+				guids = append(guids, candidate.Transaction.GUID)
+			}
+		}
+
+		table.Append([]string{
+			result.Line.BookingDate.Format("2006-01-02"),
+			amount,
+			result.Line.Description(),
+			string(result.Status),
+			strings.Join(guids, ", "),
+		})
+	}
+}
+
+func renderDuplicateGroups(table *tablewriter.Table, opts RendererOpts, groups []match.DuplicateGroup) {
+	table.Header([]string{"Group", "Date", "Entered", "Description", "Accounts", "Amount", "Transaction", "Keep"})
+	for i, group := range groups {
+		for j, transaction := range group.Transactions {
+			postDate, enterDate, description := "", "", ""
+			if transaction.PostDate != nil {
+				postDate = transaction.PostDate.Format("2006-01-02")
+			}
+			if transaction.EnterDate != nil {
+				enterDate = transaction.EnterDate.Format("2006-01-02 15:04:05")
+			}
+			if transaction.Description != nil {
+				description = *transaction.Description
+			}
+
+			var accounts []string
+			amount := 0.0
+			for _, split := range transaction.Splits {
+				accounts = append(accounts, accountName(opts, split.Account, split.AccountGUID))
+				if split.ValueDenom != 0 && split.ValueNum > 0 {
+					amount += float64(split.ValueNum) / float64(split.ValueDenom)
+				}
+			}
+
+			keep := ""
+			if j == 0 {
+				keep = "yes"
+			}
+
+			table.Append([]string{
+				fmt.Sprintf("%d", i+1),
+				postDate,
+				enterDate,
+				description,
+				strings.Join(accounts, ", "),
+				fmt.Sprintf("%.2f", amount),
+				transaction.GUID,
+				keep,
+			})
+		}
+	}
+}
+
+func renderRuleHits(table *tablewriter.Table, hits []rules.Hit) {
+	table.Header([]string{"Rule", "Transactions"})
+	for _, hit := range hits {
+		table.Append([]string{
+			hit.Rule,
+			fmt.Sprintf("%d", hit.Transactions),
+		})
+	}
+}
+
+func renderJournalEntries(table *tablewriter.Table, entries []*journal.Entry) {
+	table.Header([]string{"ID", "Time", "Command", "Changes", "Status"})
+	for _, entry := range entries {
+		status := ""
+		switch {
+		case entry.UndoneBy != 0:
+			status = fmt.Sprintf("undone by %d", entry.UndoneBy)
+		case entry.Undoes != 0:
+			status = fmt.Sprintf("undoes %d", entry.Undoes)
+		case entry.Restore != "":
+			status = fmt.Sprintf("restored %s", entry.Restore)
+		}
+
+		table.Append([]string{
+			fmt.Sprintf("%d", entry.ID),
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			entry.Command,
+			fmt.Sprintf("%d", len(entry.Changes)),
+			status,
+		})
+	}
+}
+
+func renderBackups(table *tablewriter.Table, backups []*backup.Backup) {
+	table.Header([]string{"ID", "Time", "Size", "Path"})
+	for _, b := range backups {
+		table.Append([]string{
+			b.ID,
+			b.Time.Local().Format("2006-01-02 15:04:05"),
+			fmt.Sprintf("%d", b.Size),
+			b.Path,
+		})
+	}
+}
+
+func renderChanges(table *tablewriter.Table, changes []store.Change) {
+	table.Header([]string{"Table", "ID", "Op", "Column", "Old", "New"})
+	for _, change := range changes {
+		for idx, diff := range change.Diff() {
+			tableName, id, op := change.Table, change.ID, string(change.Op)
+			if idx > 0 {
+				tableName, id, op = "", "", ""
+			}
+			table.Append([]string{
+				tableName,
+				id,
+				op,
+				diff.Column,
+				formatValue(diff.Old),
+				formatValue(diff.New),
+			})
+		}
+	}
+}
+
+func renderIssues(table *tablewriter.Table, issues []store.Issue) {
+	table.Header([]string{"Severity", "Kind", "Table", "GUID", "Message"})
+	for _, issue := range issues {
+		table.Append([]string{
+			string(issue.Severity),
+			issue.Kind,
+			issue.Table,
+			issue.GUID,
+			issue.Message,
+		})
+	}
+}
+
+func renderFixes(table *tablewriter.Table, fixes []store.Fix) {
+	table.Header([]string{"Kind", "Table", "GUID", "Fix"})
+	for _, fix := range fixes {
+		table.Append([]string{
+			fix.Kind,
+			fix.Table,
+			fix.GUID,
+			fix.Message,
+		})
+	}
+}
+
 func (t *TableRenderer) Render(w io.Writer, data any, opts ...RendererOptsFunc) error {
 
 	o := defaultRendererOpts()
@@ -210,6 +461,26 @@ func (t *TableRenderer) Render(w io.Writer, data any, opts ...RendererOptsFunc)
 		renderTransactions(table, *o, []*store.Transaction{v})
 	case []*store.Transaction:
 		renderTransactions(table, *o, v)
+	case []rules.Hit:
+		renderRuleHits(table, v)
+	case []store.Change:
+		renderChanges(table, v)
+	case []*journal.Entry:
+		renderJournalEntries(table, v)
+	case []*backup.Backup:
+		renderBackups(table, v)
+	case []*store.RegisterEntry:
+		renderRegister(table, v)
+	case *reconcile.Reconciliation:
+		renderReconciliation(table, v)
+	case []match.Result:
+		renderMatchResults(table, v)
+	case []match.DuplicateGroup:
+		renderDuplicateGroups(table, *o, v)
+	case []store.Issue:
+		renderIssues(table, v)
+	case []store.Fix:
+		renderFixes(table, v)
 	default:
 		return fmt.Errorf("unsupported model type: %T", data)
 	}
@@ -233,3 +504,17 @@ func formatAmount(valueNum, valueDenom int64) (debit, credit string) {
 
 	return "", ""
 }
+
+func formatDecimal(num, denom int64) string {
+	if denom == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", float64(num)/float64(denom))
+}
+
+func formatValue(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
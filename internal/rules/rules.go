@@ -0,0 +1,313 @@
+// Package rules implements ordered categorisation rules that are matched
+// against transactions and rewrite their splits and description.
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"time"
+
+	"gt/internal/store"
+)
+
+var ErrRuleInvalid = errors.New("invalid rule")
+
+// File is the on disk format of a rules file.
+type File struct {
+	Rules []*Rule `json:"rules"`
+}
+
+type Rule struct {
+	Name    string  `json:"name"`
+	Match   Match   `json:"match"`
+	Actions Actions `json:"actions"`
+
+	descriptionRe *regexp.Regexp
+	memoRe        *regexp.Regexp
+	minAmount     *big.Rat
+	maxAmount     *big.Rat
+	after         *time.Time
+	before        *time.Time
+	accountGUID   string
+	fromGUID      string
+	to            *store.Account
+}
+
+// Match holds the conditions of a rule. All conditions that are set must
+// hold for a transaction to match.
+type Match struct {
+	// Description is a regular expression matched against the transaction
+	// description.
+	Description string `json:"description,omitempty"`
+	// Memo is a regular expression matched against the memo of any split.
+	Memo string `json:"memo,omitempty"`
+	// Account is the guid or full name of an account the transaction must
+	// have a split on.
+	Account string `json:"account,omitempty"`
+	// MinAmount and MaxAmount bound the transaction amount, which is the sum
+	// of its debit splits.
+	MinAmount string `json:"min_amount,omitempty"`
+	MaxAmount string `json:"max_amount,omitempty"`
+	// After and Before bound the post date (YYYY-MM-DD, inclusive).
+	After  string `json:"after,omitempty"`
+	Before string `json:"before,omitempty"`
+}
+
+type Actions struct {
+	Move *Move `json:"move,omitempty"`
+	// SetMemo sets the memo of the moved split, or of the split on the
+	// matched account when there is no move action.
+	SetMemo *string `json:"set_memo,omitempty"`
+	// SetDescription replaces the description. When the rule matches on
+	// description, $1 style references to its capture groups are expanded.
+	SetDescription *string `json:"set_description,omitempty"`
+}
+
+// Move re-points splits on the From account to the To account.
+type Move struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Hit is the number of transactions a rule changed.
+type Hit struct {
+	Rule         string
+	Transactions int
+}
+
+// Load reads and compiles the rules file at path.
+func Load(path string) ([]*Rule, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f File
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for idx, rule := range f.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", idx+1)
+		}
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("%w %s: %v", ErrRuleInvalid, rule.Name, err)
+		}
+	}
+
+	return f.Rules, nil
+}
+
+func (r *Rule) compile() error {
+	var err error
+	if r.Match.Description != "" {
+		if r.descriptionRe, err = regexp.Compile(r.Match.Description); err != nil {
+			return err
+		}
+	}
+
+	if r.Match.Memo != "" {
+		if r.memoRe, err = regexp.Compile(r.Match.Memo); err != nil {
+			return err
+		}
+	}
+
+	if r.Match.MinAmount != "" {
+		var ok bool
+		if r.minAmount, ok = new(big.Rat).SetString(r.Match.MinAmount); !ok {
+			return fmt.Errorf("invalid min_amount %q", r.Match.MinAmount)
+		}
+	}
+
+	if r.Match.MaxAmount != "" {
+		var ok bool
+		if r.maxAmount, ok = new(big.Rat).SetString(r.Match.MaxAmount); !ok {
+			return fmt.Errorf("invalid max_amount %q", r.Match.MaxAmount)
+		}
+	}
+
+	if r.Match.After != "" {
+		after, err := time.Parse("2006-01-02", r.Match.After)
+		if err != nil {
+			return err
+		}
+		r.after = &after
+	}
+
+	if r.Match.Before != "" {
+		before, err := time.Parse("2006-01-02", r.Match.Before)
+		if err != nil {
+			return err
+		}
+		before = before.AddDate(0, 0, 1)
+		r.before = &before
+	}
+
+	if r.Actions.Move != nil && (r.Actions.Move.From == "" || r.Actions.Move.To == "") {
+		return fmt.Errorf("move requires from and to")
+	}
+
+	if r.Actions.SetMemo != nil && r.Actions.Move == nil && r.Match.Account == "" {
+		return fmt.Errorf("set_memo requires a move action or match account")
+	}
+
+	if r.Actions.Move == nil && r.Actions.SetMemo == nil && r.Actions.SetDescription == nil {
+		return fmt.Errorf("no actions")
+	}
+
+	return nil
+}
+
+// Resolve looks up the accounts named by the rule using resolve, which is
+// given an account guid or full account name.
+func (r *Rule) Resolve(resolve func(string) (*store.Account, error)) error {
+	if r.Match.Account != "" {
+		account, err := resolve(r.Match.Account)
+		if err != nil {
+			return fmt.Errorf("rule %s: %s: %w", r.Name, r.Match.Account, err)
+		}
+		r.accountGUID = account.GUID
+	}
+
+	if r.Actions.Move != nil {
+		from, err := resolve(r.Actions.Move.From)
+		if err != nil {
+			return fmt.Errorf("rule %s: %s: %w", r.Name, r.Actions.Move.From, err)
+		}
+		r.fromGUID = from.GUID
+
+		r.to, err = resolve(r.Actions.Move.To)
+		if err != nil {
+			return fmt.Errorf("rule %s: %s: %w", r.Name, r.Actions.Move.To, err)
+		}
+
+		// A moved split keeps its quantity which is only right in the commodity
+		// of the account it is moved from.
+		if !from.SameCommodity(r.to) {
+			return fmt.Errorf("%w %s: cannot move %s to %s which is in another commodity", ErrRuleInvalid, r.Name, from.FullName, r.to.FullName)
+		}
+	}
+
+	return nil
+}
+
+// Matches reports whether transaction satisfies every condition of the rule.
+func (r *Rule) Matches(transaction *store.Transaction) bool {
+	if r.descriptionRe != nil {
+		if transaction.Description == nil || !r.descriptionRe.MatchString(*transaction.Description) {
+			return false
+		}
+	}
+
+	if r.memoRe != nil {
+		matched := false
+		for _, split := range transaction.Splits {
+			if r.memoRe.MatchString(split.Memo) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if r.accountGUID != "" && findSplit(transaction, r.accountGUID) == nil {
+		return false
+	}
+
+	if r.fromGUID != "" && findSplit(transaction, r.fromGUID) == nil {
+		return false
+	}
+
+	if r.minAmount != nil || r.maxAmount != nil {
+		amount := transactionAmount(transaction)
+		if r.minAmount != nil && amount.Cmp(r.minAmount) < 0 {
+			return false
+		}
+		if r.maxAmount != nil && amount.Cmp(r.maxAmount) > 0 {
+			return false
+		}
+	}
+
+	if r.after != nil || r.before != nil {
+		if transaction.PostDate == nil {
+			return false
+		}
+		if r.after != nil && transaction.PostDate.Before(*r.after) {
+			return false
+		}
+		if r.before != nil && !transaction.PostDate.Before(*r.before) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Apply applies the rule's actions to transaction in memory. It returns the
+// splits that changed and whether the description changed.
+func (r *Rule) Apply(transaction *store.Transaction) ([]*store.Split, bool) {
+	var changed []*store.Split
+
+	var target *store.Split
+	if r.Actions.Move != nil {
+		target = findSplit(transaction, r.fromGUID)
+		if target != nil && target.AccountGUID != r.to.GUID {
+			target.AccountGUID = r.to.GUID
+			target.Account = r.to
+			changed = append(changed, target)
+		}
+	} else if r.accountGUID != "" {
+		target = findSplit(transaction, r.accountGUID)
+	}
+
+	if r.Actions.SetMemo != nil && target != nil && target.Memo != *r.Actions.SetMemo {
+		target.Memo = *r.Actions.SetMemo
+		if len(changed) == 0 || changed[len(changed)-1] != target {
+			changed = append(changed, target)
+		}
+	}
+
+	descriptionChanged := false
+	if r.Actions.SetDescription != nil {
+		description := *r.Actions.SetDescription
+		if r.descriptionRe != nil && transaction.Description != nil {
+			match := r.descriptionRe.FindStringSubmatchIndex(*transaction.Description)
+			description = string(r.descriptionRe.ExpandString(nil, description, *transaction.Description, match))
+		}
+		if transaction.Description == nil || *transaction.Description != description {
+			transaction.Description = &description
+			descriptionChanged = true
+		}
+	}
+
+	return changed, descriptionChanged
+}
+
+func findSplit(transaction *store.Transaction, accountGUID string) *store.Split {
+	for _, split := range transaction.Splits {
+		if split.AccountGUID == accountGUID {
+			return split
+		}
+	}
+	return nil
+}
+
+func transactionAmount(transaction *store.Transaction) *big.Rat {
+	amount := new(big.Rat)
+	for _, split := range transaction.Splits {
+		if split.ValueNum > 0 && split.ValueDenom != 0 {
+			amount.Add(amount, big.NewRat(split.ValueNum, split.ValueDenom))
+		}
+	}
+	return amount
+}
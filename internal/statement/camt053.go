@@ -0,0 +1,134 @@
+package statement
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtEntry struct {
+	Amount       camtAmount      `xml:"Amt"`
+	CdtDbtInd    string          `xml:"CdtDbtInd"`
+	BookingDate  camtDate        `xml:"BookgDt"`
+	ValueDate    camtDate        `xml:"ValDt"`
+	AcctSvcrRef  string          `xml:"AcctSvcrRef"`
+	NtryRef      string          `xml:"NtryRef"`
+	TxDetails    []camtTxDetails `xml:"NtryDtls>TxDtls"`
+	AddtlNtryInf string          `xml:"AddtlNtryInf"`
+}
+
+type camtTxDetails struct {
+	AcctSvcrRef  string   `xml:"Refs>AcctSvcrRef"`
+	EndToEndID   string   `xml:"Refs>EndToEndId"`
+	DebtorName   string   `xml:"RltdPties>Dbtr>Nm"`
+	CreditorName string   `xml:"RltdPties>Cdtr>Nm"`
+	Unstructured []string `xml:"RmtInf>Ustrd"`
+	AddtlTxInf   string   `xml:"AddtlTxInf"`
+}
+
+func (d camtDate) time() (time.Time, error) {
+	switch {
+	case d.Date != "":
+		return time.Parse("2006-01-02", strings.TrimSpace(d.Date))
+	case d.DateTime != "":
+		dt := strings.TrimSpace(d.DateTime)
+		if t, err := time.Parse(time.RFC3339, dt); err == nil {
+			return t, nil
+		}
+		return time.Parse("2006-01-02T15:04:05", dt)
+	default:
+		return time.Time{}, fmt.Errorf("missing date")
+	}
+}
+
+// ParseCAMT053 parses an ISO 20022 camt.053 bank to customer statement.
+func ParseCAMT053(r io.Reader) ([]Line, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var lines []Line
+	for _, stmt := range doc.Statements {
+		for idx, entry := range stmt.Entries {
+			date := entry.BookingDate
+			if date.Date == "" && date.DateTime == "" {
+				date = entry.ValueDate
+			}
+			bookingDate, err := date.time()
+			if err != nil {
+				return nil, fmt.Errorf("camt.053 entry %d: %w", idx+1, err)
+			}
+
+			var credit bool
+			switch entry.CdtDbtInd {
+			case "CRDT":
+				credit = true
+			case "DBIT":
+				credit = false
+			default:
+				return nil, fmt.Errorf("camt.053 entry %d: invalid credit/debit indicator %q", idx+1, entry.CdtDbtInd)
+			}
+
+			line := Line{
+				BookingDate: bookingDate,
+				Amount:      strings.TrimSpace(entry.Amount.Value),
+				Credit:      credit,
+				Currency:    entry.Amount.Currency,
+				Reference:   strings.TrimSpace(entry.AcctSvcrRef),
+			}
+
+			var remittance []string
+			for _, details := range entry.TxDetails {
+				// The counterparty is the debtor of money we receive and the
+				// creditor of money we send.
+				name := details.CreditorName
+				if credit {
+					name = details.DebtorName
+				}
+				if line.Counterparty == "" {
+					line.Counterparty = collapseSpaces(name)
+				}
+				if line.Reference == "" {
+					line.Reference = strings.TrimSpace(details.AcctSvcrRef)
+				}
+				remittance = append(remittance, details.Unstructured...)
+				if len(details.Unstructured) == 0 && details.AddtlTxInf != "" {
+					remittance = append(remittance, details.AddtlTxInf)
+				}
+			}
+			if len(remittance) == 0 && entry.AddtlNtryInf != "" {
+				remittance = append(remittance, entry.AddtlNtryInf)
+			}
+			line.Remittance = collapseSpaces(strings.Join(remittance, " "))
+
+			if line.Reference == "" {
+				line.Reference = strings.TrimSpace(entry.NtryRef)
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,181 @@
+package statement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	mt940TagRe           = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):(.*)$`)
+	mt940BalanceRe       = regexp.MustCompile(`^[CD]([0-9]{6})([A-Z]{3})`)
+	mt940StatementRe     = regexp.MustCompile(`^([0-9]{6})([0-9]{4})?(R?[CD])([A-Z])?([0-9]+,[0-9]*)([A-Z0-9]{4})([^/\n]*)(?://([^\n]*))?`)
+	mt940StructuredRe    = regexp.MustCompile(`^[0-9]{3}\?`)
+	mt940SubfieldRe      = regexp.MustCompile(`\?([0-9]{2})`)
+	mt940SwiftSubfieldRe = regexp.MustCompile(`/(NAME|REMI)/([^/]*)`)
+)
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// ParseMT940 parses a SWIFT MT940 customer statement message. Files holding
+// several messages are supported.
+func ParseMT940(r io.Reader) ([]Line, error) {
+	fields, err := readMT940Fields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []Line
+	var currency string
+	var current *Line
+	for _, field := range fields {
+		switch field.tag {
+		case "60F", "60M":
+			m := mt940BalanceRe.FindStringSubmatch(field.value)
+			if m == nil {
+				return nil, fmt.Errorf("mt940: invalid opening balance %q", field.value)
+			}
+			currency = m[2]
+		case "61":
+			line, err := parseMT940StatementLine(field.value)
+			if err != nil {
+				return nil, err
+			}
+			line.Currency = currency
+			lines = append(lines, line)
+			current = &lines[len(lines)-1]
+		case "86":
+			if current == nil {
+				continue
+			}
+			current.Counterparty, current.Remittance = parseMT940Information(field.value)
+			current = nil
+		}
+	}
+
+	return lines, nil
+}
+
+func readMT940Fields(r io.Reader) ([]mt940Field, error) {
+	var fields []mt940Field
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r ")
+		if text == "" || strings.HasPrefix(text, "{") || strings.HasPrefix(text, "-") {
+			continue
+		}
+
+		if m := mt940TagRe.FindStringSubmatch(text); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], value: m[2]})
+			continue
+		}
+
+		// Continuation of the previous field.
+		if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + text
+		}
+	}
+	return fields, scanner.Err()
+}
+
+func parseMT940StatementLine(s string) (Line, error) {
+	m := mt940StatementRe.FindStringSubmatch(s)
+	if m == nil {
+		return Line{}, fmt.Errorf("mt940: invalid statement line %q", s)
+	}
+
+	valueDate, err := time.Parse("060102", m[1])
+	if err != nil {
+		return Line{}, fmt.Errorf("mt940: invalid value date %q: %w", m[1], err)
+	}
+
+	bookingDate := valueDate
+	if m[2] != "" {
+		entryDate, err := time.Parse("0102", m[2])
+		if err != nil {
+			return Line{}, fmt.Errorf("mt940: invalid entry date %q: %w", m[2], err)
+		}
+		year := valueDate.Year()
+		// The entry date carries no year, so take it from the value date
+		// and correct for bookings made across the turn of the year.
+		switch {
+		case valueDate.Month() == time.December && entryDate.Month() == time.January:
+			year++
+		case valueDate.Month() == time.January && entryDate.Month() == time.December:
+			year--
+		}
+		bookingDate = time.Date(year, entryDate.Month(), entryDate.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	line := Line{
+		BookingDate: bookingDate,
+		Amount:      strings.Replace(m[5], ",", ".", 1),
+		// A reversal of a debit (RD) is a credit and vice versa.
+		Credit: m[3] == "C" || m[3] == "RD",
+	}
+	if strings.HasSuffix(line.Amount, ".") {
+		line.Amount += "0"
+	}
+
+	bankReference := strings.TrimSpace(m[8])
+	customerReference := strings.TrimSpace(m[7])
+	switch {
+	case bankReference != "":
+		line.Reference = bankReference
+	case customerReference != "" && customerReference != "NONREF":
+		line.Reference = customerReference
+	}
+
+	return line, nil
+}
+
+// parseMT940Information extracts the counterparty name and remittance
+// information from a :86: field. Both the structured ?NN subfield layout
+// used by German banks and /NAME/ and /REMI/ subfields are understood; any
+// other content is treated as remittance information.
+func parseMT940Information(s string) (counterparty, remittance string) {
+	// Lines wrap between words, or before a ?NN subfield in the structured
+	// layout which must then not be split from its code.
+	s = strings.ReplaceAll(s, "\n?", "?")
+	s = strings.ReplaceAll(s, "\n", " ")
+
+	if mt940StructuredRe.MatchString(s) {
+		var name, remi []string
+		idx := mt940SubfieldRe.FindAllStringSubmatchIndex(s, -1)
+		for i, m := range idx {
+			end := len(s)
+			if i+1 < len(idx) {
+				end = idx[i+1][0]
+			}
+			code := s[m[2]:m[3]]
+			value := s[m[1]:end]
+			switch {
+			case code == "32" || code == "33":
+				name = append(name, value)
+			case (code >= "20" && code <= "29") || (code >= "60" && code <= "63"):
+				remi = append(remi, value)
+			}
+		}
+		return collapseSpaces(strings.Join(name, "")), collapseSpaces(strings.Join(remi, ""))
+	}
+
+	if matches := mt940SwiftSubfieldRe.FindAllStringSubmatch(s, -1); matches != nil {
+		for _, m := range matches {
+			switch m[1] {
+			case "NAME":
+				counterparty = collapseSpaces(m[2])
+			case "REMI":
+				remittance = collapseSpaces(m[2])
+			}
+		}
+		return counterparty, remittance
+	}
+
+	return "", collapseSpaces(s)
+}
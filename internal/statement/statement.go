@@ -0,0 +1,49 @@
+// Package statement parses bank statement files into statement lines that
+// can be imported into a gnucash book.
+package statement
+
+import (
+	"strings"
+	"time"
+
+	"gt/internal/store"
+)
+
+// Line is a single booked entry of a bank statement.
+type Line struct {
+	BookingDate time.Time
+	// Amount is the unsigned decimal amount as written in the statement.
+	Amount       string
+	Credit       bool
+	Currency     string
+	Counterparty string
+	Remittance   string
+	// Reference is the bank's reference for the entry and is used as the
+	// dedup key when importing.
+	Reference string
+}
+
+// Value returns the signed amount of the line in units of 1/denom. Credits
+// are positive and debits are negative, as seen from the statement account.
+func (l Line) Value(denom int64) (int64, error) {
+	v, err := store.ParseAmount(l.Amount, denom)
+	if err != nil {
+		return 0, err
+	}
+	if !l.Credit {
+		v = -v
+	}
+	return v, nil
+}
+
+// Description returns the text used as the imported transaction description.
+func (l Line) Description() string {
+	if l.Counterparty != "" {
+		return l.Counterparty
+	}
+	return l.Remittance
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"slices"
 	"strings"
 )
 
@@ -23,6 +22,14 @@ type Account struct {
 	Placeholder   *int64
 }
 
+// SameCommodity reports whether a and b are in the same commodity.
+func (a *Account) SameCommodity(b *Account) bool {
+	if a.CommodityGUID == nil || b.CommodityGUID == nil {
+		return a.CommodityGUID == b.CommodityGUID
+	}
+	return *a.CommodityGUID == *b.CommodityGUID
+}
+
 type AccountQuery struct {
 	whereClauses []string
 	args         []any
@@ -120,11 +127,14 @@ type AccountsStorer interface {
 	All(ctx context.Context, q *AccountQuery) ([]*Account, error)
 	Get(ctx context.Context, s string, opts ...AccountsOptFunc) (*Account, error)
 	Update(ctx context.Context, account *Account) error
+	Insert(ctx context.Context, account *Account) error
 }
 
 type AccountsStore struct {
-	db   DBTX
-	Opts AccountsOpts
+	db      DBTX
+	changes *Changes
+	tree    *accountTreeCache
+	Opts    AccountsOpts
 }
 
 type AccountsOpts struct {
@@ -145,72 +155,24 @@ func WithAccountTree(b bool) AccountsOptFunc {
 	}
 }
 
-// getFullAccountName takes a store.Account and attempts to return its full account name (e.g. expenses:dining:pizza)
-func getFullAccountName(ctx context.Context, db DBTX, account *Account) (string, error) {
-	s := []string{account.Name}
-	for account.ParentGUID != nil {
-		var err error
-		q := NewAccountQuery().Where("guid=?", account.ParentGUID)
-		row := db.QueryRowContext(ctx, q.Build(), q.Args()...)
-		account, err = scanAccount(row)
-		if err != nil {
-			return "", err
-		}
-		if strings.ToLower(account.AccountType) == "root" {
-			break
-		}
-		s = append(s, account.Name)
-	}
-	slices.Reverse(s)
-	return strings.Join(s, ":"), nil
-}
-
-func getAccountFromAccountTree(ctx context.Context, db DBTX, s string) (*Account, error) {
-	q := NewAccountQuery().Where("account_type=? AND name=? AND parent_guid IS NULL", "ROOT", "Root Account")
-	row := db.QueryRowContext(ctx, q.Build(), q.Args()...)
-	rootAccount, err := scanAccount(row)
-	if err != nil {
-		return nil, err
-	}
-
-	parentAccounts := []*Account{rootAccount}
-	accounts := strings.Split(s, ":")
-	for idx, accountName := range accounts {
-		parentAccount := parentAccounts[idx]
-		q = NewAccountQuery().Where("name=? COLLATE NOCASE and parent_guid=?", accountName, parentAccount.GUID)
-		row := db.QueryRowContext(ctx, q.Build(), q.Args()...)
-		account, err := scanAccount(row)
-		if err != nil {
-			return nil, err
-		}
-		parentAccounts = append(parentAccounts, account)
-	}
-
-	if len(parentAccounts) != len(accounts)+1 {
-		return nil, fmt.Errorf("failed to find account from tree")
-	}
-
-	return parentAccounts[len(parentAccounts)-1], nil
-}
-
 func (s AccountsStore) Get(ctx context.Context, guidOrName string, opts ...AccountsOptFunc) (*Account, error) {
-	var account *Account
 	o := defaultAccountsOpts()
 	for _, fn := range opts {
 		fn(o)
 	}
 
+	tree, err := s.tree.get(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
 	if o.withAccountTree {
-		account, err := getAccountFromAccountTree(ctx, s.db, guidOrName)
-		if err != nil {
-			return nil, err
-		}
-		fullName, err := getFullAccountName(ctx, s.db, account)
+		account, err := tree.Lookup(guidOrName)
 		if err != nil {
 			return nil, err
 		}
-		account.FullName = fullName
-		return account, nil
+		found := *account
+		return &found, nil
 	}
 
 	q := NewAccountQuery()
@@ -224,17 +186,17 @@ func (s AccountsStore) Get(ctx context.Context, guidOrName string, opts ...Accou
 	if err != nil {
 		return nil, err
 	}
-
-	fullName, err := getFullAccountName(ctx, s.db, account)
-	if err != nil {
-		return nil, err
-	}
-	account.FullName = fullName
+	account.FullName = tree.FullName(account.GUID)
 
 	return account, nil
 }
 
 func (s AccountsStore) All(ctx context.Context, q *AccountQuery) ([]*Account, error) {
+	tree, err := s.tree.get(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
 	sqlQuery := q.Build()
 	args := q.Args()
 
@@ -250,11 +212,7 @@ func (s AccountsStore) All(ctx context.Context, q *AccountQuery) ([]*Account, er
 		if err != nil {
 			return nil, err
 		}
-		fullName, err := getFullAccountName(ctx, s.db, account)
-		if err != nil {
-			return nil, err
-		}
-		account.FullName = fullName
+		account.FullName = tree.FullName(account.GUID)
 		accounts = append(accounts, account)
 	}
 
@@ -377,6 +335,13 @@ WHERE guid = ? AND rowid IN (
 		}
 	}
 
+	defer a.tree.reset()
+
+	old, err := a.changes.before(ctx, a.db, "accounts", account.GUID)
+	if err != nil {
+		return err
+	}
+
 	result, err := a.db.ExecContext(
 		ctx,
 		query,
@@ -407,5 +372,50 @@ WHERE guid = ? AND rowid IN (
 		return sql.ErrNoRows
 	}
 
-	return nil
+	return a.changes.after(ctx, a.db, "accounts", account.GUID, old)
+}
+
+func (a AccountsStore) Insert(ctx context.Context, account *Account) error {
+	query := `
+INSERT INTO accounts (
+	guid,
+	name,
+	account_type,
+	commodity_guid,
+	commodity_scu,
+	non_std_scu,
+	parent_guid,
+	code,
+	description,
+	hidden,
+	placeholder
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+	if account.GUID == "" {
+		account.GUID = NewGUID()
+	}
+
+	defer a.tree.reset()
+
+	_, err := a.db.ExecContext(
+		ctx,
+		query,
+		account.GUID,
+		account.Name,
+		account.AccountType,
+		account.CommodityGUID,
+		account.CommoditySCU,
+		account.NonSTDSCU,
+		account.ParentGUID,
+		account.Code,
+		account.Description,
+		account.Hidden,
+		account.Placeholder,
+	)
+	if err != nil {
+		return err
+	}
+
+	return a.changes.after(ctx, a.db, "accounts", account.GUID, nil)
 }
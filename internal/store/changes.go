@@ -0,0 +1,309 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrRowChanged = errors.New("row changed since it was written")
+
+type ChangeOp string
+
+const (
+	ChangeOpInsert ChangeOp = "insert"
+	ChangeOpUpdate ChangeOp = "update"
+	ChangeOpDelete ChangeOp = "delete"
+)
+
+// Row holds the column values of a database row.
+type Row map[string]any
+
+// Change is a row that was inserted, updated or deleted. Old is nil for
+// inserts and New is nil for deletes.
+type Change struct {
+	Table string
+	ID    string
+	Op    ChangeOp
+	Old   Row
+	New   Row
+}
+
+// ColumnDiff is a column whose value differs between the old and new row.
+type ColumnDiff struct {
+	Column string
+	Old    any
+	New    any
+}
+
+// Diff returns the columns that differ between the old and new row, ordered
+// by column name.
+func (c Change) Diff() []ColumnDiff {
+	columns := make(map[string]bool)
+	for column := range c.Old {
+		columns[column] = true
+	}
+	for column := range c.New {
+		columns[column] = true
+	}
+
+	names := make([]string, 0, len(columns))
+	for column := range columns {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+
+	var diffs []ColumnDiff
+	for _, column := range names {
+		var oldValue, newValue any
+		if c.Old != nil {
+			oldValue = c.Old[column]
+		}
+		if c.New != nil {
+			newValue = c.New[column]
+		}
+		if c.Op == ChangeOpUpdate && fmt.Sprint(oldValue) == fmt.Sprint(newValue) {
+			continue
+		}
+		diffs = append(diffs, ColumnDiff{Column: column, Old: oldValue, New: newValue})
+	}
+	return diffs
+}
+
+// Changes records every row a Store changes within a database transaction.
+type Changes struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+// All returns the recorded changes in the order they were made.
+func (c *Changes) All() []Change {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Change(nil), c.changes...)
+}
+
+// before returns the current row identified by id, to be passed to after
+// once the row has been written.
+func (c *Changes) before(ctx context.Context, db DBTX, table, id string) (Row, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return selectRow(ctx, db, table, id)
+}
+
+// after records the change made to the row identified by id.
+func (c *Changes) after(ctx context.Context, db DBTX, table, id string, old Row) error {
+	if c == nil {
+		return nil
+	}
+
+	row, err := selectRow(ctx, db, table, id)
+	if err != nil {
+		return err
+	}
+
+	change := Change{Table: table, ID: id, Old: old, New: row}
+	switch {
+	case old == nil && row == nil:
+		return nil
+	case old == nil:
+		change.Op = ChangeOpInsert
+	case row == nil:
+		change.Op = ChangeOpDelete
+	default:
+		change.Op = ChangeOpUpdate
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = append(c.changes, change)
+	return nil
+}
+
+// primaryKey returns the primary key column of a gnucash table.
+func primaryKey(table string) string {
+	if table == "slots" {
+		return "id"
+	}
+	return "guid"
+}
+
+// selectRow returns every column of the row identified by id or nil when
+// the row does not exist.
+func selectRow(ctx context.Context, db DBTX, table, id string) (Row, error) {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s = ?", table, primaryKey(table))
+	rows, err := db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	row := make(Row, len(columns))
+	for i, column := range columns {
+		if b, ok := values[i].([]byte); ok {
+			row[column] = string(b)
+			continue
+		}
+		row[column] = values[i]
+	}
+
+	return row, rows.Err()
+}
+
+// UnmarshalJSON decodes a row keeping integer columns as int64 rather than
+// float64 so rows read back from a journal can be compared and written.
+func (r *Row) UnmarshalJSON(b []byte) error {
+	var values map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return err
+	}
+
+	if values == nil {
+		*r = nil
+		return nil
+	}
+
+	row := make(Row, len(values))
+	for column, value := range values {
+		if n, ok := value.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				row[column] = i
+				continue
+			}
+			f, err := n.Float64()
+			if err != nil {
+				return err
+			}
+			row[column] = f
+			continue
+		}
+		row[column] = value
+	}
+	*r = row
+	return nil
+}
+
+// equal reports whether both rows hold the same columns and values.
+func (r Row) equal(other Row) bool {
+	if (r == nil) != (other == nil) || len(r) != len(other) {
+		return false
+	}
+	for column, value := range r {
+		otherValue, ok := other[column]
+		if !ok || fmt.Sprint(value) != fmt.Sprint(otherValue) {
+			return false
+		}
+	}
+	return true
+}
+
+var columnNameRe = regexp.MustCompile(`^[a-z_]+$`)
+
+func validateChange(c Change) error {
+	switch c.Table {
+	case "accounts", "splits", "transactions", "slots":
+	default:
+		return fmt.Errorf("unsupported table %q", c.Table)
+	}
+
+	for _, row := range []Row{c.Old, c.New} {
+		for column := range row {
+			if !columnNameRe.MatchString(column) {
+				return fmt.Errorf("%s: invalid column %q", c.Table, column)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Revert undoes change by writing back the old row. It fails with
+// ErrRowChanged when the row no longer holds the values change wrote.
+func (s *Store) Revert(ctx context.Context, c Change) error {
+	db := s.dbtx
+	if err := validateChange(c); err != nil {
+		return err
+	}
+
+	current, err := selectRow(ctx, db, c.Table, c.ID)
+	if err != nil {
+		return err
+	}
+	if !current.equal(c.New) {
+		return fmt.Errorf("%w: %s %s", ErrRowChanged, c.Table, c.ID)
+	}
+
+	switch c.Op {
+	case ChangeOpInsert:
+		_, err = db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s = ?", c.Table, primaryKey(c.Table)), c.ID)
+	case ChangeOpDelete:
+		columns := c.Old.columns()
+		placeholders := make([]string, len(columns))
+		args := make([]any, len(columns))
+		for i, column := range columns {
+			placeholders[i] = "?"
+			args[i] = c.Old[column]
+		}
+		_, err = db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", c.Table, strings.Join(columns, ", "), strings.Join(placeholders, ", ")), args...)
+	case ChangeOpUpdate:
+		columns := c.Old.columns()
+		assignments := make([]string, len(columns))
+		args := make([]any, 0, len(columns)+1)
+		for i, column := range columns {
+			assignments[i] = column + " = ?"
+			args = append(args, c.Old[column])
+		}
+		args = append(args, c.ID)
+		_, err = db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", c.Table, strings.Join(assignments, ", "), primaryKey(c.Table)), args...)
+	default:
+		return fmt.Errorf("unsupported change op %q", c.Op)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.Table == "accounts" {
+		s.tree.reset()
+	}
+
+	return s.changes.after(ctx, db, c.Table, c.ID, current)
+}
+
+func (r Row) columns() []string {
+	columns := make([]string, 0, len(r))
+	for column := range r {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
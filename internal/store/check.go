@@ -0,0 +1,396 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Kinds of problems Check reports.
+const (
+	IssueUnbalancedTransaction   = "unbalanced-transaction"
+	IssueEmptyTransaction        = "empty-transaction"
+	IssueSplitMissingAccount     = "split-missing-account"
+	IssueSplitMissingTransaction = "split-missing-transaction"
+	IssueAccountMissingParent    = "account-missing-parent"
+	IssueAccountParentCycle      = "account-parent-cycle"
+	IssueValueQuantityMismatch   = "value-quantity-mismatch"
+	IssuePlaceholderSplit        = "placeholder-split"
+	IssueDuplicateAccountName    = "duplicate-account-name"
+	IssueInvalidDate             = "invalid-date"
+)
+
+// Issue is a problem found in the book.
+type Issue struct {
+	Severity Severity
+	Kind     string
+	Table    string
+	GUID     string
+	Message  string
+}
+
+// Check scans the book for problems.
+func (s *Store) Check(ctx context.Context) ([]Issue, error) {
+	checks := []func(context.Context, DBTX) ([]Issue, error){
+		checkAccounts,
+		checkSplitReferences,
+		checkTransactionBalances,
+		checkValueQuantity,
+		checkPlaceholderSplits,
+		checkDates,
+	}
+
+	issues := []Issue{}
+	for _, check := range checks {
+		found, err := check(ctx, s.dbtx)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, found...)
+	}
+
+	return issues, nil
+}
+
+func checkAccounts(ctx context.Context, db DBTX) ([]Issue, error) {
+	rows, err := db.QueryContext(ctx, "SELECT guid, name, account_type, parent_guid FROM accounts ORDER BY guid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type node struct {
+		guid        string
+		name        string
+		accountType string
+		parentGUID  *string
+	}
+
+	var nodes []*node
+	byGUID := make(map[string]*node)
+	for rows.Next() {
+		var n node
+		var parentGUID sql.NullString
+		if err := rows.Scan(&n.guid, &n.name, &n.accountType, &parentGUID); err != nil {
+			return nil, err
+		}
+		if parentGUID.Valid {
+			n.parentGUID = &parentGUID.String
+		}
+		nodes = append(nodes, &n)
+		byGUID[n.guid] = &n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	siblings := make(map[string]*node)
+	for _, n := range nodes {
+		if n.parentGUID == nil {
+			if !strings.EqualFold(n.accountType, "root") {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Kind:     IssueAccountMissingParent,
+					Table:    "accounts",
+					GUID:     n.guid,
+					Message:  fmt.Sprintf("account %q has no parent", n.name),
+				})
+			}
+			continue
+		}
+
+		if _, ok := byGUID[*n.parentGUID]; !ok {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Kind:     IssueAccountMissingParent,
+				Table:    "accounts",
+				GUID:     n.guid,
+				Message:  fmt.Sprintf("account %q has missing parent %s", n.name, *n.parentGUID),
+			})
+		}
+
+		// gt looks up accounts by name without regard to case so names that
+		// only differ in case are ambiguous as well.
+		key := *n.parentGUID + ":" + strings.ToLower(n.name)
+		if sibling, ok := siblings[key]; ok {
+			issues = append(issues, Issue{
+				Severity: SeverityWarning,
+				Kind:     IssueDuplicateAccountName,
+				Table:    "accounts",
+				GUID:     n.guid,
+				Message:  fmt.Sprintf("account %q has the same name as sibling %s", n.name, sibling.guid),
+			})
+		} else {
+			siblings[key] = n
+		}
+
+		// Only the accounts in a cycle are reported, the walk up from an
+		// account below a cycle stops once it is in the cycle.
+		seen := map[string]bool{n.guid: true}
+		for parent := byGUID[*n.parentGUID]; parent != nil && parent.parentGUID != nil; parent = byGUID[*parent.parentGUID] {
+			if parent.guid == n.guid {
+				issues = append(issues, Issue{
+					Severity: SeverityError,
+					Kind:     IssueAccountParentCycle,
+					Table:    "accounts",
+					GUID:     n.guid,
+					Message:  fmt.Sprintf("account %q is its own ancestor", n.name),
+				})
+				break
+			}
+			if seen[parent.guid] {
+				break
+			}
+			seen[parent.guid] = true
+		}
+	}
+
+	return issues, nil
+}
+
+func checkSplitReferences(ctx context.Context, db DBTX) ([]Issue, error) {
+	var issues []Issue
+
+	found, err := queryIssues(ctx, db, `
+SELECT guid, account_guid FROM splits
+WHERE account_guid NOT IN (SELECT guid FROM accounts)
+ORDER BY guid
+`, func(guid, accountGUID string) Issue {
+		return Issue{
+			Severity: SeverityError,
+			Kind:     IssueSplitMissingAccount,
+			Table:    "splits",
+			GUID:     guid,
+			Message:  fmt.Sprintf("split references missing account %s", accountGUID),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, found...)
+
+	found, err = queryIssues(ctx, db, `
+SELECT guid, tx_guid FROM splits
+WHERE tx_guid NOT IN (SELECT guid FROM transactions)
+ORDER BY guid
+`, func(guid, txGUID string) Issue {
+		return Issue{
+			Severity: SeverityError,
+			Kind:     IssueSplitMissingTransaction,
+			Table:    "splits",
+			GUID:     guid,
+			Message:  fmt.Sprintf("split references missing transaction %s", txGUID),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, found...)
+
+	found, err = queryIssues(ctx, db, `
+SELECT guid, COALESCE(description, '') FROM transactions
+WHERE guid NOT IN (SELECT tx_guid FROM splits)
+ORDER BY guid
+`, func(guid, description string) Issue {
+		return Issue{
+			Severity: SeverityWarning,
+			Kind:     IssueEmptyTransaction,
+			Table:    "transactions",
+			GUID:     guid,
+			Message:  fmt.Sprintf("transaction %q has no splits", description),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, found...)
+
+	return issues, nil
+}
+
+func checkTransactionBalances(ctx context.Context, db DBTX) ([]Issue, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT transactions.guid, splits.guid, splits.value_num, splits.value_denom
+FROM transactions
+JOIN splits ON splits.tx_guid = transactions.guid
+ORDER BY transactions.guid
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []Issue
+	var current string
+	var balance *big.Rat
+	flush := func() {
+		if balance != nil && balance.Sign() != 0 {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Kind:     IssueUnbalancedTransaction,
+				Table:    "transactions",
+				GUID:     current,
+				Message:  fmt.Sprintf("transaction is unbalanced by %s", balance.FloatString(2)),
+			})
+		}
+	}
+
+	for rows.Next() {
+		var txGUID, splitGUID string
+		var valueNum, valueDenom int64
+		if err := rows.Scan(&txGUID, &splitGUID, &valueNum, &valueDenom); err != nil {
+			return nil, err
+		}
+
+		if txGUID != current {
+			flush()
+			current = txGUID
+			balance = new(big.Rat)
+		}
+
+		if valueDenom == 0 {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Kind:     IssueUnbalancedTransaction,
+				Table:    "splits",
+				GUID:     splitGUID,
+				Message:  "split value has a zero denominator",
+			})
+			continue
+		}
+		balance.Add(balance, big.NewRat(valueNum, valueDenom))
+	}
+	flush()
+
+	return issues, rows.Err()
+}
+
+func checkValueQuantity(ctx context.Context, db DBTX) ([]Issue, error) {
+	rows, err := db.QueryContext(ctx, `
+SELECT splits.guid, splits.value_num, splits.value_denom, splits.quantity_num, splits.quantity_denom
+FROM splits
+JOIN transactions ON transactions.guid = splits.tx_guid
+JOIN accounts ON accounts.guid = splits.account_guid
+WHERE accounts.commodity_guid = transactions.currency_guid
+ORDER BY splits.guid
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []Issue
+	for rows.Next() {
+		var guid string
+		var valueNum, valueDenom, quantityNum, quantityDenom int64
+		if err := rows.Scan(&guid, &valueNum, &valueDenom, &quantityNum, &quantityDenom); err != nil {
+			return nil, err
+		}
+		if valueDenom == 0 || quantityDenom == 0 {
+			continue
+		}
+
+		value := big.NewRat(valueNum, valueDenom)
+		quantity := big.NewRat(quantityNum, quantityDenom)
+		if value.Cmp(quantity) != 0 {
+			issues = append(issues, Issue{
+				Severity: SeverityError,
+				Kind:     IssueValueQuantityMismatch,
+				Table:    "splits",
+				GUID:     guid,
+				Message:  fmt.Sprintf("split value %s differs from quantity %s in the transaction currency", value.FloatString(2), quantity.FloatString(2)),
+			})
+		}
+	}
+
+	return issues, rows.Err()
+}
+
+func checkPlaceholderSplits(ctx context.Context, db DBTX) ([]Issue, error) {
+	return queryIssues(ctx, db, `
+SELECT splits.guid, accounts.name
+FROM splits
+JOIN accounts ON accounts.guid = splits.account_guid
+WHERE accounts.placeholder = 1
+ORDER BY splits.guid
+`, func(guid, accountName string) Issue {
+		return Issue{
+			Severity: SeverityWarning,
+			Kind:     IssuePlaceholderSplit,
+			Table:    "splits",
+			GUID:     guid,
+			Message:  fmt.Sprintf("split is posted to placeholder account %q", accountName),
+		}
+	})
+}
+
+func checkDates(ctx context.Context, db DBTX) ([]Issue, error) {
+	var issues []Issue
+	for _, q := range []struct {
+		table  string
+		column string
+	}{
+		{table: "transactions", column: "post_date"},
+		{table: "transactions", column: "enter_date"},
+		{table: "splits", column: "reconcile_date"},
+	} {
+		found, err := queryIssues(ctx, db, fmt.Sprintf("SELECT guid, %s FROM %s WHERE %s IS NOT NULL ORDER BY guid", q.column, q.table, q.column), func(guid, value string) Issue {
+			if _, err := parseTime(value); err == nil {
+				return Issue{}
+			}
+			return Issue{
+				Severity: SeverityError,
+				Kind:     IssueInvalidDate,
+				Table:    q.table,
+				GUID:     guid,
+				Message:  fmt.Sprintf("%s %q is not a valid date", q.column, value),
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, found...)
+	}
+	return issues, nil
+}
+
+// queryIssues runs a query returning two string columns and turns each row
+// into an issue with fn. Rows fn returns an empty issue for are skipped.
+func queryIssues(ctx context.Context, db DBTX, query string, fn func(string, string) Issue) ([]Issue, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var issues []Issue
+	for rows.Next() {
+		var a, b string
+		if err := rows.Scan(&a, &b); err != nil {
+			return nil, err
+		}
+		if issue := fn(a, b); issue.Kind != "" {
+			issues = append(issues, issue)
+		}
+	}
+
+	return issues, rows.Err()
+}
+
+// Fix is a change made to repair an issue.
+type Fix struct {
+	Kind    string
+	Table   string
+	GUID    string
+	Message string
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+type Commodity struct {
+	GUID        string
+	Namespace   string
+	Mnemonic    string
+	Fullname    *string
+	Cusip       *string
+	Fraction    int64
+	QuoteFlag   int64
+	QuoteSource *string
+	QuoteTZ     *string
+}
+
+type CommoditiesStorer interface {
+	Get(ctx context.Context, guid string) (*Commodity, error)
+}
+
+type CommoditiesStore struct {
+	db DBTX
+}
+
+func (c CommoditiesStore) Get(ctx context.Context, guid string) (*Commodity, error) {
+	query := `
+SELECT
+	guid,
+	namespace,
+	mnemonic,
+	fullname,
+	cusip,
+	fraction,
+	quote_flag,
+	quote_source,
+	quote_tz
+FROM commodities
+WHERE guid = ?
+`
+
+	var commodity Commodity
+	var fullname, cusip, quoteSource, quoteTZ sql.NullString
+	err := c.db.QueryRowContext(ctx, query, guid).Scan(
+		&commodity.GUID,
+		&commodity.Namespace,
+		&commodity.Mnemonic,
+		&fullname,
+		&cusip,
+		&commodity.Fraction,
+		&commodity.QuoteFlag,
+		&quoteSource,
+		&quoteTZ,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if fullname.Valid {
+		commodity.Fullname = &fullname.String
+	}
+
+	if cusip.Valid {
+		commodity.Cusip = &cusip.String
+	}
+
+	if quoteSource.Valid {
+		commodity.QuoteSource = &quoteSource.String
+	}
+
+	if quoteTZ.Valid {
+		commodity.QuoteTZ = &quoteTZ.String
+	}
+
+	return &commodity, nil
+}
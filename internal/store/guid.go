@@ -0,0 +1,16 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// NewGUID returns a random 32 character hex guid in the format gnucash uses
+// for its primary keys.
+func NewGUID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
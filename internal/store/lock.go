@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// Lock is a row of the gnclock table gnucash writes while a book is open.
+type Lock struct {
+	Hostname string
+	PID      int64
+}
+
+func (s *Store) hasLockTable(ctx context.Context) (bool, error) {
+	var name string
+	err := s.dbtx.QueryRowContext(ctx, "SELECT name FROM sqlite_master WHERE type='table' AND name='gnclock'").Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Locks returns the current holders of the book lock. Books without a
+// gnclock table have no holders.
+func (s *Store) Locks(ctx context.Context) ([]Lock, error) {
+	ok, err := s.hasLockTable(ctx)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	rows, err := s.dbtx.QueryContext(ctx, "SELECT Hostname, PID FROM gnclock")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var locks []Lock
+	for rows.Next() {
+		var hostname sql.NullString
+		var pid sql.NullInt64
+		if err := rows.Scan(&hostname, &pid); err != nil {
+			return nil, err
+		}
+		locks = append(locks, Lock{Hostname: hostname.String, PID: pid.Int64})
+	}
+
+	return locks, rows.Err()
+}
+
+// Lock adds lock to the gnclock table so gnucash warns when the book is
+// opened while it is held.
+func (s *Store) Lock(ctx context.Context, lock Lock) error {
+	ok, err := s.hasLockTable(ctx)
+	if err != nil || !ok {
+		return err
+	}
+
+	_, err = s.dbtx.ExecContext(ctx, "INSERT INTO gnclock (Hostname, PID) VALUES (?, ?)", lock.Hostname, lock.PID)
+	return err
+}
+
+// ReplaceLocks makes locks the only holders of the book lock.
+func (s *Store) ReplaceLocks(ctx context.Context, locks []Lock) error {
+	ok, err := s.hasLockTable(ctx)
+	if err != nil || !ok {
+		return err
+	}
+
+	if _, err := s.dbtx.ExecContext(ctx, "DELETE FROM gnclock"); err != nil {
+		return err
+	}
+	for _, lock := range locks {
+		if _, err := s.dbtx.ExecContext(ctx, "INSERT INTO gnclock (Hostname, PID) VALUES (?, ?)", lock.Hostname, lock.PID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unlock removes lock from the gnclock table.
+func (s *Store) Unlock(ctx context.Context, lock Lock) error {
+	ok, err := s.hasLockTable(ctx)
+	if err != nil || !ok {
+		return err
+	}
+
+	_, err = s.dbtx.ExecContext(ctx, "DELETE FROM gnclock WHERE Hostname = ? AND PID = ?", lock.Hostname, lock.PID)
+	return err
+}
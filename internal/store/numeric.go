@@ -0,0 +1,49 @@
+package store
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ParseAmount parses a decimal amount (e.g. 42.10, 1,234.56 or -3,50) and
+// returns its numerator for the given denominator. A comma is the decimal
+// separator in an amount without a point and a thousands separator
+// otherwise. Amounts that can not be represented exactly with denom are
+// rejected rather than rounded.
+func ParseAmount(s string, denom int64) (int64, error) {
+	if strings.Contains(s, ".") {
+		s = strings.ReplaceAll(s, ",", "")
+	} else {
+		s = strings.ReplaceAll(s, ",", ".")
+	}
+	s = strings.TrimSpace(s)
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount: %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt64(denom))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("amount %q has more precision than 1/%d", s, denom)
+	}
+	if !r.Num().IsInt64() {
+		return 0, fmt.Errorf("amount %q out of range", s)
+	}
+	return r.Num().Int64(), nil
+}
+
+// RatToNum returns r as a numerator of denom, rounded half away from zero.
+func RatToNum(r *big.Rat, denom int64) int64 {
+	scaled := new(big.Rat).Mul(r, big.NewRat(denom, 1))
+	num, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	if new(big.Int).Abs(new(big.Int).Mul(rem, big.NewInt(2))).Cmp(scaled.Denom()) >= 0 {
+		num.Add(num, big.NewInt(int64(scaled.Sign())))
+	}
+	return num.Int64()
+}
+
+// ConvertNum returns num/fromDenom as a numerator of toDenom, rounded half
+// away from zero.
+func ConvertNum(num, fromDenom, toDenom int64) int64 {
+	return RatToNum(big.NewRat(num, fromDenom), toDenom)
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+// SplitTransactionAccount is shown as the counter account of a register
+// entry whose transaction has more than one other split, as gnucash does.
+const SplitTransactionAccount = "-- Split Transaction --"
+
+// RegisterEntry is a row of an account register, one per split on the
+// account. Amount and Balance are in the account's commodity and share
+// Denom.
+type RegisterEntry struct {
+	TXGUID         string
+	SplitGUID      string
+	PostDate       *time.Time
+	Num            string
+	Description    string
+	Memo           string
+	CounterAccount string
+	ReconcileState string
+	AmountNum      int64
+	BalanceNum     int64
+	Denom          int64
+}
+
+// Register returns the register of account with a running balance in post
+// date order. Only splits posted within [from, to) are returned but the
+// balance includes every split posted before from. A nil from or to leaves
+// that end open.
+func (s *Store) Register(ctx context.Context, account *Account, from, to *time.Time) ([]*RegisterEntry, error) {
+	denom := account.CommoditySCU
+	if denom == 0 {
+		denom = 100
+	}
+
+	balance := new(big.Rat)
+	if from != nil {
+		rows, err := s.dbtx.QueryContext(ctx, `
+SELECT splits.quantity_num, splits.quantity_denom
+FROM splits
+JOIN transactions ON transactions.guid = splits.tx_guid
+WHERE splits.account_guid = ? AND transactions.post_date < ?
+`, account.GUID, from.UTC().Format("2006-01-02 15:04:05"))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var quantityNum, quantityDenom int64
+			if err := rows.Scan(&quantityNum, &quantityDenom); err != nil {
+				return nil, err
+			}
+			if quantityDenom != 0 {
+				balance.Add(balance, big.NewRat(quantityNum, quantityDenom))
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	q := NewTransactionQuery().
+		WhereAccount(account.GUID, false).
+		OrderBy("post_date", false).
+		OrderBy("enter_date", false)
+	if from != nil {
+		q.Where("transactions.post_date >= ?", from.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if to != nil {
+		q.Where("transactions.post_date < ?", to.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	transactions, err := s.Transactions.All(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*RegisterEntry{}
+	for _, transaction := range transactions {
+		for _, split := range transaction.Splits {
+			if split.AccountGUID != account.GUID {
+				continue
+			}
+
+			amount := new(big.Rat)
+			if split.QuantityDenom != 0 {
+				amount.SetFrac64(split.QuantityNum, split.QuantityDenom)
+			}
+			balance.Add(balance, amount)
+
+			entry := &RegisterEntry{
+				TXGUID:         transaction.GUID,
+				SplitGUID:      split.GUID,
+				PostDate:       transaction.PostDate,
+				Num:            transaction.Num,
+				Memo:           split.Memo,
+				CounterAccount: counterAccount(transaction, split),
+				ReconcileState: split.ReconcileState,
+				AmountNum:      RatToNum(amount, denom),
+				BalanceNum:     RatToNum(balance, denom),
+				Denom:          denom,
+			}
+			if transaction.Description != nil {
+				entry.Description = *transaction.Description
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// counterAccount returns the full name of the account on the other side of
+// split, SplitTransactionAccount when there is more than one.
+func counterAccount(transaction *Transaction, split *Split) string {
+	var others []*Split
+	for _, other := range transaction.Splits {
+		if other != split {
+			others = append(others, other)
+		}
+	}
+
+	switch {
+	case len(others) == 0:
+		return ""
+	case len(others) > 1:
+		return SplitTransactionAccount
+	case others[0].Account == nil:
+		return others[0].AccountGUID
+	default:
+		return others[0].Account.FullName
+	}
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrSchemaUnsupported = errors.New("unsupported gnucash schema")
+
+// tableVersions holds the table versions of the gnucash releases gt
+// supports. The first version of each table is the one gt writes, the
+// others are older versions gt can only read.
+var tableVersions = map[string][]int64{
+	"accounts":     {1},
+	"commodities":  {1},
+	"slots":        {4, 3},
+	"splits":       {5, 4},
+	"transactions": {4, 3},
+}
+
+// gnucashVersions bounds the Gnucash versions, in the MMmmmmm format gnucash
+// records them in, of the supported releases.
+var gnucashVersions = struct {
+	min int64
+	max int64
+}{
+	min: 2060000,
+	max: 5999999,
+}
+
+// Schema is the schema version of a book as recorded in its versions table.
+type Schema struct {
+	Versions map[string]int64
+}
+
+// Schema reads the versions table of the book.
+func (s *Store) Schema(ctx context.Context) (*Schema, error) {
+	rows, err := s.dbtx.QueryContext(ctx, "SELECT table_name, table_version FROM versions")
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return &Schema{Versions: map[string]int64{}}, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	schema := &Schema{Versions: map[string]int64{}}
+	for rows.Next() {
+		var name string
+		var version int64
+		if err := rows.Scan(&name, &version); err != nil {
+			return nil, err
+		}
+		schema.Versions[name] = version
+	}
+
+	return schema, rows.Err()
+}
+
+// CheckReadable returns an error naming every table gt can not read.
+func (s *Schema) CheckReadable() error {
+	return s.check(false)
+}
+
+// CheckWritable returns an error naming every table gt can not write.
+func (s *Schema) CheckWritable() error {
+	return s.check(true)
+}
+
+func (s *Schema) check(write bool) error {
+	var problems []string
+
+	if version, ok := s.Versions["Gnucash"]; !ok {
+		problems = append(problems, "missing Gnucash version")
+	} else if version < gnucashVersions.min || version > gnucashVersions.max {
+		problems = append(problems, fmt.Sprintf("Gnucash version %d", version))
+	}
+
+	// Gnucash-Resave is the oldest release that can save the book, gnucash
+	// writes it as a constant (19920) rather than the release that saved
+	// it. Only a book that needs a newer release than gt supports is
+	// refused.
+	if version, ok := s.Versions["Gnucash-Resave"]; ok && version > gnucashVersions.max {
+		problems = append(problems, fmt.Sprintf("Gnucash-Resave version %d", version))
+	}
+
+	tables := make([]string, 0, len(tableVersions))
+	for table := range tableVersions {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		supported := tableVersions[table]
+		version, ok := s.Versions[table]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("missing %s version", table))
+			continue
+		}
+
+		if write {
+			supported = supported[:1]
+		}
+
+		known := false
+		for _, v := range supported {
+			if v == version {
+				known = true
+				break
+			}
+		}
+		if !known {
+			problems = append(problems, fmt.Sprintf("%s version %d", table, version))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrSchemaUnsupported, strings.Join(problems, ", "))
+	}
+
+	return nil
+}
+
+// parseTime parses a gnucash timestamp. Releases before gnucash 3 store
+// timestamps as YYYYMMDDHHMMSS rather than YYYY-MM-DD HH:MM:SS.
+func parseTime(s string) (time.Time, error) {
+	if len(s) == len("20060102150405") {
+		return time.Parse("20060102150405", s)
+	}
+	return time.Parse("2006-01-02 15:04:05", s)
+}
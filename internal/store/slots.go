@@ -0,0 +1,359 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Slot types as stored in slots.slot_type by gnucash.
+const (
+	SlotTypeInt64    int64 = 1
+	SlotTypeDouble   int64 = 2
+	SlotTypeNumeric  int64 = 3
+	SlotTypeString   int64 = 4
+	SlotTypeGUID     int64 = 5
+	SlotTypeTimespec int64 = 6
+	SlotTypeFrame    int64 = 9
+	SlotTypeGDate    int64 = 10
+)
+
+type Slot struct {
+	ID              int64
+	ObjGUID         string
+	Name            string
+	SlotType        int64
+	Int64Val        *int64
+	StringVal       *string
+	DoubleVal       *float64
+	TimespecVal     *time.Time
+	GUIDVal         *string
+	NumericValNum   *int64
+	NumericValDenom *int64
+	GDateVal        *string
+}
+
+// NewStringSlot returns a string slot name of the object identified by
+// objGUID.
+func NewStringSlot(objGUID, name, value string) *Slot {
+	return &Slot{ObjGUID: objGUID, Name: name, SlotType: SlotTypeString, StringVal: &value}
+}
+
+// NewNumericSlot returns a numeric slot name of the object identified by
+// objGUID holding num/denom.
+func NewNumericSlot(objGUID, name string, num, denom int64) *Slot {
+	return &Slot{ObjGUID: objGUID, Name: name, SlotType: SlotTypeNumeric, NumericValNum: &num, NumericValDenom: &denom}
+}
+
+type SlotQuery struct {
+	whereClauses []string
+	args         []any
+	orderFields  []orderField
+	limit        *int
+	offset       *int
+}
+
+func NewSlotQuery() *SlotQuery {
+	return &SlotQuery{
+		whereClauses: make([]string, 0),
+		args:         make([]any, 0),
+		orderFields:  make([]orderField, 0),
+	}
+}
+
+func (q *SlotQuery) Where(clause string, args ...any) *SlotQuery {
+	q.whereClauses = append(q.whereClauses, clause)
+	q.args = append(q.args, args...)
+	return q
+}
+
+func (q *SlotQuery) OrderBy(field string, descending bool) *SlotQuery {
+	q.orderFields = append(q.orderFields, orderField{field: field, descending: descending})
+	return q
+}
+
+func (q *SlotQuery) Limit(limit int) *SlotQuery {
+	q.limit = &limit
+	return q
+}
+
+func (q *SlotQuery) Offset(offset int) *SlotQuery {
+	q.offset = &offset
+	return q
+}
+
+func (q *SlotQuery) Build() string {
+	var b strings.Builder
+	b.WriteString(`
+SELECT
+	id,
+	obj_guid,
+	name,
+	slot_type,
+	int64_val,
+	string_val,
+	double_val,
+	timespec_val,
+	guid_val,
+	numeric_val_num,
+	numeric_val_denom,
+	gdate_val
+FROM slots
+`)
+
+	if len(q.whereClauses) > 0 {
+		b.WriteString("\nWHERE ")
+		b.WriteString(strings.Join(q.whereClauses, " AND "))
+	}
+
+	if len(q.orderFields) > 0 {
+		b.WriteString("\nORDER BY ")
+		orders := make([]string, len(q.orderFields))
+		for i, field := range q.orderFields {
+			direction := "ASC"
+			if field.descending {
+				direction = "DESC"
+			}
+			orders[i] = fmt.Sprintf("%s %s", field.field, direction)
+		}
+		b.WriteString(strings.Join(orders, ", "))
+	}
+
+	if q.limit != nil {
+		b.WriteString(fmt.Sprintf("\nLIMIT %d", *q.limit))
+	}
+
+	if q.offset != nil {
+		b.WriteString(fmt.Sprintf("\nOFFSET %d", *q.offset))
+	}
+
+	return b.String()
+}
+
+func (q *SlotQuery) Args() []any {
+	return q.args
+}
+
+type SlotsStorer interface {
+	All(ctx context.Context, q *SlotQuery) ([]*Slot, error)
+	Insert(ctx context.Context, slot *Slot) error
+	Delete(ctx context.Context, slot *Slot) error
+	Get(ctx context.Context, objGUID, name string) (*Slot, error)
+	Set(ctx context.Context, slot *Slot) error
+	DeleteNamed(ctx context.Context, objGUID string, names ...string) error
+}
+
+type SlotsStore struct {
+	db      DBTX
+	changes *Changes
+}
+
+func (s SlotsStore) All(ctx context.Context, q *SlotQuery) ([]*Slot, error) {
+	rows, err := s.db.QueryContext(ctx, q.Build(), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var slots []*Slot
+	for rows.Next() {
+		var slot Slot
+		var int64Val, numericValNum, numericValDenom sql.NullInt64
+		var stringVal, timespecVal, guidVal, gdateVal sql.NullString
+		var doubleVal sql.NullFloat64
+
+		err := rows.Scan(
+			&slot.ID,
+			&slot.ObjGUID,
+			&slot.Name,
+			&slot.SlotType,
+			&int64Val,
+			&stringVal,
+			&doubleVal,
+			&timespecVal,
+			&guidVal,
+			&numericValNum,
+			&numericValDenom,
+			&gdateVal,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if int64Val.Valid {
+			slot.Int64Val = &int64Val.Int64
+		}
+
+		if stringVal.Valid {
+			slot.StringVal = &stringVal.String
+		}
+
+		if doubleVal.Valid {
+			slot.DoubleVal = &doubleVal.Float64
+		}
+
+		if timespecVal.Valid && timespecVal.String != "" {
+			ts, err := parseTime(timespecVal.String)
+			if err != nil {
+				return nil, err
+			}
+			slot.TimespecVal = &ts
+		}
+
+		if guidVal.Valid {
+			slot.GUIDVal = &guidVal.String
+		}
+
+		if numericValNum.Valid {
+			slot.NumericValNum = &numericValNum.Int64
+		}
+
+		if numericValDenom.Valid {
+			slot.NumericValDenom = &numericValDenom.Int64
+		}
+
+		if gdateVal.Valid {
+			slot.GDateVal = &gdateVal.String
+		}
+
+		slots = append(slots, &slot)
+	}
+
+	return slots, rows.Err()
+}
+
+// Insert inserts slot and sets slot.ID to the id assigned by the database.
+func (s SlotsStore) Insert(ctx context.Context, slot *Slot) error {
+	query := `
+INSERT INTO slots (
+	obj_guid,
+	name,
+	slot_type,
+	int64_val,
+	string_val,
+	double_val,
+	timespec_val,
+	guid_val,
+	numeric_val_num,
+	numeric_val_denom,
+	gdate_val
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+	var timespecVal sql.NullString
+	if slot.TimespecVal != nil {
+		timespecVal = sql.NullString{
+			String: slot.TimespecVal.UTC().Format("2006-01-02 15:04:05"),
+			Valid:  true,
+		}
+	}
+
+	result, err := s.db.ExecContext(
+		ctx,
+		query,
+		slot.ObjGUID,
+		slot.Name,
+		slot.SlotType,
+		slot.Int64Val,
+		slot.StringVal,
+		slot.DoubleVal,
+		timespecVal,
+		slot.GUIDVal,
+		slot.NumericValNum,
+		slot.NumericValDenom,
+		slot.GDateVal,
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	slot.ID = id
+
+	return s.changes.after(ctx, s.db, "slots", strconv.FormatInt(slot.ID, 10), nil)
+}
+
+func (s SlotsStore) Delete(ctx context.Context, slot *Slot) error {
+	id := strconv.FormatInt(slot.ID, 10)
+	old, err := s.changes.before(ctx, s.db, "slots", id)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM slots WHERE id = ?", slot.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return s.changes.after(ctx, s.db, "slots", id, old)
+}
+
+// Get returns the slot name of the object identified by objGUID, or nil when
+// the object has no such slot.
+func (s SlotsStore) Get(ctx context.Context, objGUID, name string) (*Slot, error) {
+	slots, err := s.All(ctx, NewSlotQuery().
+		Where("obj_guid=?", objGUID).
+		Where("name=?", name).
+		Limit(1))
+	if err != nil || len(slots) == 0 {
+		return nil, err
+	}
+	return slots[0], nil
+}
+
+// Set inserts slot, replacing the slots of the same name its object already
+// has.
+func (s SlotsStore) Set(ctx context.Context, slot *Slot) error {
+	if err := s.DeleteNamed(ctx, slot.ObjGUID, slot.Name); err != nil {
+		return err
+	}
+	return s.Insert(ctx, slot)
+}
+
+// DeleteNamed deletes the slots with the given names of the object
+// identified by objGUID.
+func (s SlotsStore) DeleteNamed(ctx context.Context, objGUID string, names ...string) error {
+	for _, name := range names {
+		slots, err := s.All(ctx, NewSlotQuery().
+			Where("obj_guid=?", objGUID).
+			Where("name=?", name))
+		if err != nil {
+			return err
+		}
+		for _, slot := range slots {
+			if err := s.Delete(ctx, slot); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deleteObject deletes the slots of the object identified by guid.
+func (s SlotsStore) deleteObject(ctx context.Context, guid string) error {
+	slots, err := s.All(ctx, NewSlotQuery().Where("obj_guid=?", guid))
+	if err != nil {
+		return err
+	}
+	for _, slot := range slots {
+		if err := s.Delete(ctx, slot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -121,10 +121,13 @@ func (q *SplitQuery) Args() []any {
 type SplitsStorer interface {
 	All(ctx context.Context, q *SplitQuery) ([]*Split, error)
 	Update(ctx context.Context, split *Split) error
+	Insert(ctx context.Context, split *Split) error
+	Delete(ctx context.Context, split *Split) error
 }
 
 type SplitsStore struct {
-	db DBTX
+	db      DBTX
+	changes *Changes
 }
 
 func (s SplitsStore) All(ctx context.Context, q *SplitQuery) ([]*Split, error) {
@@ -170,7 +173,7 @@ func (s *SplitsStore) scanSplits(rows *sql.Rows) ([]*Split, error) {
 		}
 
 		if reconcileDate.Valid {
-			rd, err := time.Parse("2006-01-02 15:04:05", reconcileDate.String)
+			rd, err := parseTime(reconcileDate.String)
 			if err != nil {
 				return nil, err
 			}
@@ -221,6 +224,11 @@ WHERE guid = ?
 		}
 	}
 
+	old, err := s.changes.before(ctx, s.db, "splits", split.GUID)
+	if err != nil {
+		return err
+	}
+
 	result, err := s.db.ExecContext(
 		ctx,
 		query,
@@ -250,5 +258,87 @@ WHERE guid = ?
 		return sql.ErrNoRows
 	}
 
-	return nil
+	return s.changes.after(ctx, s.db, "splits", split.GUID, old)
+}
+
+func (s SplitsStore) Insert(ctx context.Context, split *Split) error {
+	query := `
+INSERT INTO splits (
+	guid,
+	tx_guid,
+	account_guid,
+	memo,
+	action,
+	reconcile_state,
+	reconcile_date,
+	value_num,
+	value_denom,
+	quantity_num,
+	quantity_denom,
+	lot_guid
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+	if split.GUID == "" {
+		split.GUID = NewGUID()
+	}
+
+	var reconcileDate sql.NullString
+	if split.ReconcileDate != nil {
+		reconcileDate = sql.NullString{
+			String: split.ReconcileDate.Format("2006-01-02 15:04:05"),
+			Valid:  true,
+		}
+	}
+
+	_, err := s.db.ExecContext(
+		ctx,
+		query,
+		split.GUID,
+		split.TXGUID,
+		split.AccountGUID,
+		split.Memo,
+		split.Action,
+		split.ReconcileState,
+		reconcileDate,
+		split.ValueNum,
+		split.ValueDenom,
+		split.QuantityNum,
+		split.QuantityDenom,
+		split.LogGUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.changes.after(ctx, s.db, "splits", split.GUID, nil)
+}
+
+// Delete deletes split and its slots.
+func (s SplitsStore) Delete(ctx context.Context, split *Split) error {
+	slots := SlotsStore{db: s.db, changes: s.changes}
+	if err := slots.deleteObject(ctx, split.GUID); err != nil {
+		return err
+	}
+
+	old, err := s.changes.before(ctx, s.db, "splits", split.GUID)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM splits WHERE guid = ?", split.GUID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return s.changes.after(ctx, s.db, "splits", split.GUID, old)
 }
@@ -4,34 +4,84 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 )
 
 type Store struct {
 	db           *sql.DB
+	dbtx         DBTX
+	changes      *Changes
+	tree         *accountTreeCache
 	Transactions TransactionsStorer
 	Splits       SplitsStorer
 	Accounts     AccountsStorer
+	Slots        SlotsStorer
+	Commodities  CommoditiesStorer
 }
 
 func NewStore(db *sql.DB) Store {
+	tree := &accountTreeCache{}
 	return Store{
 		db:           db,
-		Transactions: TransactionsStore{db: db},
+		dbtx:         db,
+		tree:         tree,
+		Transactions: TransactionsStore{db: db, tree: tree},
 		Splits:       SplitsStore{db: db},
-		Accounts:     AccountsStore{db: db},
+		Accounts:     AccountsStore{db: db, tree: tree},
+		Slots:        SlotsStore{db: db},
+		Commodities:  CommoditiesStore{db: db},
 	}
 }
 
+// WithTx returns a Store that runs its queries in tx and records the rows
+// it changes.
 func (s *Store) WithTx(tx *sql.Tx) *Store {
+	changes := &Changes{}
+	tree := &accountTreeCache{}
 	return &Store{
 		db:           s.db,
-		Transactions: TransactionsStore{db: tx},
-		Splits:       SplitsStore{db: tx},
-		Accounts:     AccountsStore{db: tx},
+		dbtx:         tx,
+		changes:      changes,
+		tree:         tree,
+		Transactions: TransactionsStore{db: tx, changes: changes, tree: tree},
+		Splits:       SplitsStore{db: tx, changes: changes},
+		Accounts:     AccountsStore{db: tx, changes: changes, tree: tree},
+		Slots:        SlotsStore{db: tx, changes: changes},
+		Commodities:  CommoditiesStore{db: tx},
 	}
 }
 
-func (s *Store) ExecTx(ctx context.Context, fn func(*Store) error) error {
+// AccountTree returns the account tree of the book, loading it on first use.
+func (s *Store) AccountTree(ctx context.Context) (*AccountTree, error) {
+	return s.tree.get(ctx, s.dbtx)
+}
+
+// Changes returns the rows changed through a Store returned by WithTx.
+func (s *Store) Changes() []Change {
+	return s.changes.All()
+}
+
+type ExecTxOpts struct {
+	beforeCommit func([]Change) (bool, error)
+}
+
+type ExecTxOptFunc func(*ExecTxOpts)
+
+// WithBeforeCommit sets a function that is given the changes made by the
+// transaction before it is committed. The transaction is rolled back
+// without error when fn returns false.
+func WithBeforeCommit(fn func([]Change) (bool, error)) ExecTxOptFunc {
+	return func(o *ExecTxOpts) {
+		o.beforeCommit = fn
+	}
+}
+
+func (s *Store) ExecTx(ctx context.Context, fn func(*Store) error, opts ...ExecTxOptFunc) error {
+	o := &ExecTxOpts{}
+	for _, optFn := range opts {
+		optFn(o)
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -45,7 +95,28 @@ func (s *Store) ExecTx(ctx context.Context, fn func(*Store) error) error {
 		return err
 	}
 
-	return tx.Commit()
+	if o.beforeCommit != nil {
+		commit, err := o.beforeCommit(txStore.Changes())
+		if err != nil || !commit {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+			}
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, change := range txStore.Changes() {
+		if change.Table == "accounts" {
+			s.tree.reset()
+			break
+		}
+	}
+
+	return nil
 }
 
 type orderField struct {
@@ -68,3 +139,10 @@ var (
 	_ DBTX = (*sql.DB)(nil)
 	_ DBTX = (*sql.Tx)(nil)
 )
+
+// ReadOnlyDSN returns the sqlite data source name opening the book at path
+// read only. immutable=1 is not used as gnucash may write to the book while
+// it is being read.
+func ReadOnlyDSN(path string) string {
+	return "file:" + strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path) + "?mode=ro"
+}
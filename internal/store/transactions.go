@@ -18,6 +18,13 @@ type Transaction struct {
 	Splits       []*Split
 }
 
+// DatePost returns the time gnucash posts a transaction dated on the day of
+// date at: 10:59 UTC, which falls on the same calendar day in every
+// timezone.
+func DatePost(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 10, 59, 0, 0, time.UTC)
+}
+
 type TransactionQuery struct {
 	whereClauses []string
 	args         []any
@@ -40,6 +47,22 @@ func (q *TransactionQuery) Where(clause string, args ...any) *TransactionQuery {
 	return q
 }
 
+// WhereAccount limits the query to transactions with a split on the account
+// identified by guid or, with includeChildren, on any account below it.
+func (q *TransactionQuery) WhereAccount(guid string, includeChildren bool) *TransactionQuery {
+	if !includeChildren {
+		return q.Where("transactions.guid IN (SELECT tx_guid FROM splits WHERE account_guid=?)", guid)
+	}
+	return q.Where(`transactions.guid IN (
+	WITH RECURSIVE subtree(guid) AS (
+		SELECT ?
+		UNION
+		SELECT accounts.guid FROM accounts JOIN subtree ON accounts.parent_guid = subtree.guid
+	)
+	SELECT tx_guid FROM splits WHERE account_guid IN (SELECT guid FROM subtree)
+)`, guid)
+}
+
 func (q *TransactionQuery) OrderBy(field string, descending bool) *TransactionQuery {
 	q.orderFields = append(q.orderFields, orderField{field: field, descending: descending})
 	return q
@@ -163,20 +186,192 @@ func (q *TransactionQuery) Args() []any {
 type TransactionsStorer interface {
 	All(ctx context.Context, q *TransactionQuery) ([]*Transaction, error)
 	Get(ctx context.Context, guid string) (*Transaction, error)
+	Insert(ctx context.Context, transaction *Transaction) error
+	Update(ctx context.Context, transaction *Transaction) error
+	Delete(ctx context.Context, transaction *Transaction) error
 }
 
 type TransactionsStore struct {
-	db DBTX
+	db      DBTX
+	changes *Changes
+	tree    *accountTreeCache
+}
+
+// Insert inserts transaction and all of its splits. Missing guids are
+// generated and each split's TXGUID is set to the transaction guid.
+func (t TransactionsStore) Insert(ctx context.Context, transaction *Transaction) error {
+	query := `
+INSERT INTO transactions (
+	guid,
+	currency_guid,
+	num,
+	post_date,
+	enter_date,
+	description
+) VALUES (?, ?, ?, ?, ?, ?)
+`
+
+	if transaction.GUID == "" {
+		transaction.GUID = NewGUID()
+	}
+
+	var postDate sql.NullString
+	if transaction.PostDate != nil {
+		postDate = sql.NullString{
+			String: transaction.PostDate.UTC().Format("2006-01-02 15:04:05"),
+			Valid:  true,
+		}
+	}
+
+	var enterDate sql.NullString
+	if transaction.EnterDate != nil {
+		enterDate = sql.NullString{
+			String: transaction.EnterDate.UTC().Format("2006-01-02 15:04:05"),
+			Valid:  true,
+		}
+	}
+
+	_, err := t.db.ExecContext(
+		ctx,
+		query,
+		transaction.GUID,
+		transaction.CurrencyGUID,
+		transaction.Num,
+		postDate,
+		enterDate,
+		transaction.Description,
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := t.changes.after(ctx, t.db, "transactions", transaction.GUID, nil); err != nil {
+		return err
+	}
+
+	splits := SplitsStore{db: t.db, changes: t.changes}
+	for _, split := range transaction.Splits {
+		split.TXGUID = transaction.GUID
+		if err := splits.Insert(ctx, split); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Update updates the transaction row only, splits are updated through
+// SplitsStore.
+func (t TransactionsStore) Update(ctx context.Context, transaction *Transaction) error {
+	query := `
+UPDATE transactions
+SET
+	currency_guid = ?,
+	num = ?,
+	post_date = ?,
+	enter_date = ?,
+	description = ?
+WHERE guid = ?
+`
+
+	var postDate sql.NullString
+	if transaction.PostDate != nil {
+		postDate = sql.NullString{
+			String: transaction.PostDate.UTC().Format("2006-01-02 15:04:05"),
+			Valid:  true,
+		}
+	}
+
+	var enterDate sql.NullString
+	if transaction.EnterDate != nil {
+		enterDate = sql.NullString{
+			String: transaction.EnterDate.UTC().Format("2006-01-02 15:04:05"),
+			Valid:  true,
+		}
+	}
+
+	old, err := t.changes.before(ctx, t.db, "transactions", transaction.GUID)
+	if err != nil {
+		return err
+	}
+
+	result, err := t.db.ExecContext(
+		ctx,
+		query,
+		transaction.CurrencyGUID,
+		transaction.Num,
+		postDate,
+		enterDate,
+		transaction.Description,
+		transaction.GUID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return t.changes.after(ctx, t.db, "transactions", transaction.GUID, old)
+}
+
+// Delete deletes transaction with its splits and the slots of both.
+func (t TransactionsStore) Delete(ctx context.Context, transaction *Transaction) error {
+	splits := SplitsStore{db: t.db, changes: t.changes}
+	slots := SlotsStore{db: t.db, changes: t.changes}
+
+	if err := slots.deleteObject(ctx, transaction.GUID); err != nil {
+		return err
+	}
+
+	for _, split := range transaction.Splits {
+		if err := splits.Delete(ctx, split); err != nil {
+			return err
+		}
+	}
+
+	old, err := t.changes.before(ctx, t.db, "transactions", transaction.GUID)
+	if err != nil {
+		return err
+	}
+
+	result, err := t.db.ExecContext(ctx, "DELETE FROM transactions WHERE guid = ?", transaction.GUID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return t.changes.after(ctx, t.db, "transactions", transaction.GUID, old)
 }
 
 func (t TransactionsStore) Get(ctx context.Context, guid string) (*Transaction, error) {
+	tree, err := t.tree.get(ctx, t.db)
+	if err != nil {
+		return nil, err
+	}
+
 	q := NewTransactionQuery().Where("transactions.guid=?", guid)
 	rows, err := t.db.QueryContext(ctx, q.Build(), q.Args()...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	transactions, err := scanTransactions(rows)
+	transactions, err := scanTransactions(rows, tree)
 	if err != nil {
 		return nil, err
 	}
@@ -189,7 +384,6 @@ func (t TransactionsStore) Get(ctx context.Context, guid string) (*Transaction,
 }
 
 func (t TransactionsStore) All(ctx context.Context, q *TransactionQuery) ([]*Transaction, error) {
-
 	var guidQuery strings.Builder
 	guidQuery.WriteString("SELECT guid FROM transactions")
 
@@ -219,54 +413,32 @@ func (t TransactionsStore) All(ctx context.Context, q *TransactionQuery) ([]*Tra
 		guidQuery.WriteString(fmt.Sprintf("\nOFFSET %d", *q.offset))
 	}
 
-	guidRows, err := t.db.QueryContext(ctx, guidQuery.String(), q.Args()...)
-	if err != nil {
-		return nil, err
-	}
-	defer guidRows.Close()
-
-	var guids []string
-	for guidRows.Next() {
-		var guid string
-		if err := guidRows.Scan(&guid); err != nil {
-			return nil, err
-		}
-		guids = append(guids, guid)
-	}
-
-	if err := guidRows.Err(); err != nil {
-		return nil, err
-	}
-
-	transactions := []*Transaction{}
-	if len(guids) == 0 {
-		return transactions, nil
-	}
-
-	placeholders := make([]string, len(guids))
-	guidArgs := make([]any, len(guids))
-	for i, guid := range guids {
-		placeholders[i] = "?"
-		guidArgs[i] = guid
-	}
-
+	// The transactions are selected by a subquery rather than a list of
+	// their guids which could exceed the number of variables sqlite binds.
 	fullQuery := NewTransactionQuery()
-	fullQuery.Where(fmt.Sprintf("transactions.guid IN (%s)", strings.Join(placeholders, ",")), guidArgs...)
+	fullQuery.Where(fmt.Sprintf("transactions.guid IN (%s)", guidQuery.String()), q.Args()...)
 
 	for _, orderField := range q.orderFields {
 		fullQuery.OrderBy(orderField.field, orderField.descending)
 	}
 
+	tree, err := t.tree.get(ctx, t.db)
+	if err != nil {
+		return nil, err
+	}
+
 	rows, err := t.db.QueryContext(ctx, fullQuery.Build(), fullQuery.Args()...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	return scanTransactions(rows)
+	return scanTransactions(rows, tree)
 }
 
-func scanTransactions(rows *sql.Rows) ([]*Transaction, error) {
+// scanTransactions scans transactions joined with their splits and accounts,
+// taking the accounts' full names from tree.
+func scanTransactions(rows *sql.Rows, tree *AccountTree) ([]*Transaction, error) {
 	transactionMap := make(map[string]*Transaction)
 	var orderedGUIDs []string
 
@@ -324,7 +496,7 @@ func scanTransactions(rows *sql.Rows) ([]*Transaction, error) {
 			}
 
 			if transactionPostDate.Valid {
-				pd, err := time.Parse("2006-01-02 15:04:05", transactionPostDate.String)
+				pd, err := parseTime(transactionPostDate.String)
 				if err != nil {
 					return nil, err
 				}
@@ -332,7 +504,7 @@ func scanTransactions(rows *sql.Rows) ([]*Transaction, error) {
 			}
 
 			if transactionEnterDate.Valid {
-				ed, err := time.Parse("2006-01-02 15:04:05", transactionEnterDate.String)
+				ed, err := parseTime(transactionEnterDate.String)
 				if err != nil {
 					return nil, err
 				}
@@ -374,7 +546,7 @@ func scanTransactions(rows *sql.Rows) ([]*Transaction, error) {
 			}
 
 			if splitReconcileDate.Valid {
-				rd, err := time.Parse("2006-01-02 15:04:05", splitReconcileDate.String)
+				rd, err := parseTime(splitReconcileDate.String)
 				if err != nil {
 					return nil, err
 				}
@@ -394,6 +566,22 @@ func scanTransactions(rows *sql.Rows) ([]*Transaction, error) {
 					NonSTDSCU:    accountNonStdSCU.Int64,
 				}
 
+				if accountCommodityGUID.Valid {
+					account.CommodityGUID = &accountCommodityGUID.String
+				}
+
+				if accountParentGUID.Valid {
+					account.ParentGUID = &accountParentGUID.String
+				}
+
+				if accountCode.Valid {
+					account.Code = &accountCode.String
+				}
+
+				if accountDescription.Valid {
+					account.Description = &accountDescription.String
+				}
+
 				if accountHidden.Valid {
 					account.Hidden = &accountHidden.Int64
 				}
@@ -402,6 +590,7 @@ func scanTransactions(rows *sql.Rows) ([]*Transaction, error) {
 					account.Placeholder = &accountPlaceholder.Int64
 				}
 
+				account.FullName = tree.FullName(account.GUID)
 				split.Account = &account
 			}
 
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// AccountTree is the account hierarchy of a book held in memory so that full
+// names, parents, children and paths are resolved without a query per
+// ancestor.
+type AccountTree struct {
+	root      *Account
+	accounts  map[string]*Account
+	children  map[string][]*Account
+	fullNames map[string]string
+}
+
+// LoadAccountTree loads every account of the book with a single query.
+func LoadAccountTree(ctx context.Context, db DBTX) (*AccountTree, error) {
+	q := NewAccountQuery()
+	rows, err := db.QueryContext(ctx, q.Build(), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	t := &AccountTree{
+		accounts:  make(map[string]*Account),
+		children:  make(map[string][]*Account),
+		fullNames: make(map[string]string),
+	}
+
+	var accounts []*Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+
+		// gnucash books hold duplicate rows for some accounts, the first row
+		// wins as it does for the queries elsewhere.
+		if _, ok := t.accounts[account.GUID]; ok {
+			continue
+		}
+		t.accounts[account.GUID] = account
+		if t.root == nil && account.ParentGUID == nil && strings.EqualFold(account.AccountType, "root") && account.Name == "Root Account" {
+			t.root = account
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, account := range accounts {
+		if t.accounts[account.GUID] != account || account.ParentGUID == nil {
+			continue
+		}
+		t.children[*account.ParentGUID] = append(t.children[*account.ParentGUID], account)
+	}
+
+	for _, account := range accounts {
+		account.FullName = t.FullName(account.GUID)
+	}
+
+	return t, nil
+}
+
+// Root returns the root account of the book or nil when the book has none.
+func (t *AccountTree) Root() *Account {
+	return t.root
+}
+
+// Get returns the account identified by guid.
+func (t *AccountTree) Get(guid string) (*Account, bool) {
+	account, ok := t.accounts[guid]
+	return account, ok
+}
+
+// Parent returns the parent of the account identified by guid or nil.
+func (t *AccountTree) Parent(guid string) *Account {
+	account, ok := t.accounts[guid]
+	if !ok || account.ParentGUID == nil {
+		return nil
+	}
+	return t.accounts[*account.ParentGUID]
+}
+
+// Children returns the direct children of the account identified by guid.
+func (t *AccountTree) Children(guid string) []*Account {
+	return t.children[guid]
+}
+
+// Descendants returns every account below the account identified by guid.
+func (t *AccountTree) Descendants(guid string) []*Account {
+	var descendants []*Account
+	seen := map[string]bool{guid: true}
+	queue := []string{guid}
+	for len(queue) > 0 {
+		for _, child := range t.children[queue[0]] {
+			if seen[child.GUID] {
+				continue
+			}
+			seen[child.GUID] = true
+			descendants = append(descendants, child)
+			queue = append(queue, child.GUID)
+		}
+		queue = queue[1:]
+	}
+	return descendants
+}
+
+// FullName returns the full name (e.g. expenses:dining:pizza) of the account
+// identified by guid. The root account is left out and an orphaned account's
+// name stops at its missing parent.
+func (t *AccountTree) FullName(guid string) string {
+	if fullName, ok := t.fullNames[guid]; ok {
+		return fullName
+	}
+
+	account, ok := t.accounts[guid]
+	if !ok {
+		return ""
+	}
+
+	s := []string{account.Name}
+	seen := map[string]bool{account.GUID: true}
+	for account.ParentGUID != nil {
+		parent, ok := t.accounts[*account.ParentGUID]
+		if !ok || seen[parent.GUID] || strings.EqualFold(parent.AccountType, "root") {
+			break
+		}
+		seen[parent.GUID] = true
+		account = parent
+		s = append(s, account.Name)
+	}
+	slices.Reverse(s)
+
+	fullName := strings.Join(s, ":")
+	t.fullNames[guid] = fullName
+	return fullName
+}
+
+// Lookup returns the account with the full name path (e.g.
+// expenses:dining:pizza), matching names without regard to case. It returns
+// sql.ErrNoRows when there is no such account.
+func (t *AccountTree) Lookup(path string) (*Account, error) {
+	if t.root == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	account := t.root
+	for _, name := range strings.Split(path, ":") {
+		var next *Account
+		for _, child := range t.children[account.GUID] {
+			if strings.EqualFold(child.Name, name) {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil, sql.ErrNoRows
+		}
+		account = next
+	}
+
+	return account, nil
+}
+
+// accountTreeCache holds the account tree of a Store, loaded on first use and
+// dropped whenever the accounts are changed through the Store.
+type accountTreeCache struct {
+	mu   sync.Mutex
+	tree *AccountTree
+}
+
+func (c *accountTreeCache) get(ctx context.Context, db DBTX) (*AccountTree, error) {
+	if c == nil {
+		return LoadAccountTree(ctx, db)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tree == nil {
+		tree, err := LoadAccountTree(ctx, db)
+		if err != nil {
+			return nil, err
+		}
+		c.tree = tree
+	}
+	return c.tree, nil
+}
+
+func (c *accountTreeCache) reset() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tree = nil
+}